@@ -0,0 +1,45 @@
+package logger
+
+import "regexp"
+
+// Placeholder styles for the numericPlaceholder of ExplainSQL.
+var (
+	DollarPlaceholder = regexp.MustCompile(`\$(\d+)`) // postgres $1
+	AtPPlaceholder    = regexp.MustCompile(`@p(\d+)`) // mssql @p1
+	ColonPlaceholder  = regexp.MustCompile(`:(\d+)`)  // oracle :1
+)
+
+// Dialect is the placeholder style and the string escaping of a database.
+type Dialect struct {
+	// NumericPlaceholder matches the numbered placeholders, the number being its first group, nil for `?`.
+	NumericPlaceholder *regexp.Regexp
+	// Escaper quotes the strings, a quote inside a string is doubled.
+	Escaper string
+	// EscapeBackslash doubles the backslashes inside a string, as MySQL reads them as escapes,
+	// the string literals of the statements are read likewise.
+	EscapeBackslash bool
+}
+
+// DialectOf returns the dialect of the db.system value, e.g. mysql, postgresql, mssql, oracle or sqlite.
+func DialectOf(system string) Dialect {
+	switch system {
+	case "mysql", "mariadb", "tidb":
+		return Dialect{Escaper: `'`, EscapeBackslash: true}
+	case "postgresql", "cockroachdb":
+		return Dialect{NumericPlaceholder: DollarPlaceholder, Escaper: `'`}
+	case "mssql":
+		return Dialect{NumericPlaceholder: AtPPlaceholder, Escaper: `'`}
+	case "oracle":
+		return Dialect{NumericPlaceholder: ColonPlaceholder, Escaper: `'`}
+	default:
+		return Dialect{Escaper: `'`}
+	}
+}
+
+// quoting returns how the dialect reads the backslashes inside the string literals.
+func (d Dialect) quoting() quoting {
+	if d.EscapeBackslash {
+		return backslashQuoting
+	}
+	return standardQuoting
+}
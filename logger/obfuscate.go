@@ -0,0 +1,319 @@
+package logger
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const obfuscatedLiteral = "?"
+
+type tokenKind int
+
+const (
+	tokenSpace       tokenKind = iota
+	tokenComment               // -- comment, # comment, /* comment */
+	tokenWord                  // keywords and bare identifiers
+	tokenIdentifier            // `quoted`, "quoted" identifiers
+	tokenLiteral               // 'string', "mysql string", $$string$$, 42, 4.2e1, 0x2A
+	tokenPlaceholder           // ?, $1, :1, :name, @name, $name
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// quoting is how the backslashes inside the '...' literals, and the "..." tokens, are read.
+type quoting int
+
+const (
+	standardQuoting  quoting = iota // only the E'...' literals read backslash escapes, e.g. postgres, sqlite, mssql
+	backslashQuoting                // every literal reads backslash escapes, "..." is a literal too, e.g. mysql
+	unknownQuoting                  // a literal read differently by both runs to the end of the statement, "..." is a literal
+)
+
+// ObfuscateSQL replaces the string, numeric and hex literals of sql with `?`,
+// collapses the `IN (?, ?, ?)` lists into `IN (?)` and drops the comments.
+// The placeholders and the MySQL backtick identifiers are kept,
+// so the result is safe to record whether the literals were inlined or not.
+//
+// The dialect being unknown, the double-quoted text, a MySQL string or an identifier elsewhere, is obfuscated,
+// and a string literal whose end depends on reading its backslashes as escapes, as MySQL does, or not,
+// is obfuscated with the rest of the statement. Dialect.ObfuscateSQL knows them.
+func ObfuscateSQL(sql string) string {
+	return obfuscate(sql, unknownQuoting)
+}
+
+// ObfuscateSQL is the package ObfuscateSQL reading the string literals as the dialect does,
+// the double-quoted text being an identifier which is kept, but for MySQL which reads it as a string literal.
+func (d Dialect) ObfuscateSQL(sql string) string {
+	return obfuscate(sql, d.quoting())
+}
+
+func obfuscate(sql string, q quoting) string {
+	tokens := tokenize(sql, q)
+
+	var b strings.Builder
+	b.Grow(len(sql))
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.kind {
+		case tokenComment:
+			// keep the surrounding tokens apart.
+			if b.Len() > 0 && i+1 < len(tokens) && tokens[i+1].kind != tokenSpace && tokens[i-1].kind != tokenSpace {
+				b.WriteByte(' ')
+			}
+		case tokenLiteral:
+			b.WriteString(obfuscatedLiteral)
+		case tokenWord:
+			b.WriteString(t.text)
+			if strings.EqualFold(t.text, "in") {
+				if end, ok := collapseList(tokens, i+1); ok {
+					for _, s := range tokens[i+1 : end] {
+						if s.kind == tokenSpace {
+							b.WriteString(s.text)
+							continue
+						}
+						break
+					}
+					b.WriteString("(" + obfuscatedLiteral + ")")
+					i = end
+				}
+			}
+		default:
+			b.WriteString(t.text)
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// collapseList reports whether the tokens from start are a list of literals and placeholders,
+// e.g. ` (?, 'a', 3)`, and returns the index of the closing parenthesis.
+func collapseList(tokens []token, start int) (int, bool) {
+	i := skipSpaces(tokens, start)
+	if i >= len(tokens) || tokens[i].text != "(" {
+		return 0, false
+	}
+
+	for {
+		i = skipSpaces(tokens, i+1)
+		if i >= len(tokens) || (tokens[i].kind != tokenLiteral && tokens[i].kind != tokenPlaceholder) {
+			return 0, false
+		}
+
+		i = skipSpaces(tokens, i+1)
+		if i >= len(tokens) {
+			return 0, false
+		}
+		switch tokens[i].text {
+		case ")":
+			return i, true
+		case ",":
+		default:
+			return 0, false
+		}
+	}
+}
+
+func skipSpaces(tokens []token, i int) int {
+	for i < len(tokens) && (tokens[i].kind == tokenSpace || tokens[i].kind == tokenComment) {
+		i++
+	}
+	return i
+}
+
+func tokenize(sql string, q quoting) []token {
+	var tokens []token
+
+	for i := 0; i < len(sql); {
+		kind, n := nextToken(sql[i:], q)
+		tokens = append(tokens, token{kind: kind, text: sql[i : i+n]})
+		i += n
+	}
+
+	return tokens
+}
+
+// nextToken returns the kind and the byte length of the token at the start of s.
+func nextToken(s string, q quoting) (tokenKind, int) {
+	c := s[0]
+
+	switch {
+	case isSpace(c):
+		n := 1
+		for n < len(s) && isSpace(s[n]) {
+			n++
+		}
+		return tokenSpace, n
+	case c == '-' && strings.HasPrefix(s, "--"), c == '#':
+		if n := strings.IndexByte(s, '\n'); n >= 0 {
+			return tokenComment, n
+		}
+		return tokenComment, len(s)
+	case c == '/' && strings.HasPrefix(s, "/*"):
+		if n := strings.Index(s[2:], "*/"); n >= 0 {
+			return tokenComment, n + 4
+		}
+		return tokenComment, len(s)
+	case c == '\'':
+		return tokenLiteral, literalLen(s, '\'', q, false)
+	case c == '"':
+		if q == standardQuoting {
+			return tokenIdentifier, quotedLen(s, '"', false)
+		}
+		return tokenLiteral, literalLen(s, '"', q, false)
+	case c == '`':
+		return tokenIdentifier, quotedLen(s, '`', false)
+	case c == '$':
+		if n := dollarQuotedLen(s); n > 0 {
+			return tokenLiteral, n
+		}
+		if n := digitsLen(s[1:]); n > 0 {
+			return tokenPlaceholder, n + 1
+		}
+		if len(s) > 1 && isWordStart(s[1:]) { // sqlite $name
+			return tokenPlaceholder, wordLen(s[1:]) + 1
+		}
+		return tokenPunct, 1
+	case c == '?':
+		return tokenPlaceholder, 1
+	case c == ':' && strings.HasPrefix(s, "::"): // postgres cast
+		return tokenPunct, 2
+	case c == ':' && len(s) > 1 && isDigit(s[1]): // oracle :1
+		return tokenPlaceholder, digitsLen(s[1:]) + 1
+	case (c == ':' || c == '@') && len(s) > 1 && isWordStart(s[1:]):
+		return tokenPlaceholder, wordLen(s[1:]) + 1
+	case isDigit(c) || (c == '.' && len(s) > 1 && isDigit(s[1])):
+		return tokenLiteral, numberLen(s)
+	case isWordStart(s):
+		n := wordLen(s)
+		// N'national', E'escaped', X'hex', B'bit' literals.
+		if n == 1 && len(s) > 1 && s[1] == '\'' && strings.ContainsRune("nNeExXbB", rune(c)) {
+			return tokenLiteral, 1 + literalLen(s[1:], '\'', q, c == 'e' || c == 'E')
+		}
+		return tokenWord, n
+	default:
+		_, n := utf8.DecodeRuneInString(s)
+		return tokenPunct, n
+	}
+}
+
+// quotedLen returns the length of the quoted token at the start of s, a doubled quote is an escaped one.
+func quotedLen(s string, quote byte, backslash bool) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// literalLen returns the length of the literal quoted by quote at the start of s, escaped tells whether it always reads
+// backslash escapes. With the unknown quoting, a literal read differently by both runs to the end of s.
+func literalLen(s string, quote byte, q quoting, escaped bool) int {
+	switch {
+	case escaped || q == backslashQuoting:
+		return quotedLen(s, quote, true)
+	case q == standardQuoting:
+		return quotedLen(s, quote, false)
+	}
+
+	n := quotedLen(s, quote, false)
+	if quotedLen(s, quote, true) != n {
+		return len(s)
+	}
+	return n
+}
+
+// dollarQuotedLen returns the length of the postgres $tag$string$tag$ at the start of s, or 0.
+func dollarQuotedLen(s string) int {
+	end := strings.IndexByte(s[1:], '$')
+	if end < 0 {
+		return 0
+	}
+	tag := s[:end+2]
+	for _, r := range tag[1 : len(tag)-1] {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return 0
+		}
+	}
+	if len(tag) > 2 && isDigit(tag[1]) {
+		return 0
+	}
+
+	if n := strings.Index(s[len(tag):], tag); n >= 0 {
+		return len(tag) + n + len(tag)
+	}
+	return len(s)
+}
+
+func numberLen(s string) int {
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		n := 2
+		for n < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[n]) >= 0 {
+			n++
+		}
+		return n
+	}
+
+	n := digitsLen(s)
+	if n < len(s) && s[n] == '.' {
+		n += 1 + digitsLen(s[n+1:])
+	}
+	if n < len(s) && (s[n] == 'e' || s[n] == 'E') {
+		m := n + 1
+		if m < len(s) && (s[m] == '+' || s[m] == '-') {
+			m++
+		}
+		if d := digitsLen(s[m:]); d > 0 {
+			n = m + d
+		}
+	}
+	return n
+}
+
+func digitsLen(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
+
+func wordLen(s string) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func isWordStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestObfuscateSQL(t *testing.T) {
+	results := []struct {
+		SQL    string
+		Result string
+	}{
+		{
+			SQL:    "SELECT * FROM users WHERE email = 'w@g.com' AND age > 18",
+			Result: "SELECT * FROM users WHERE email = ? AND age > ?",
+		},
+		{
+			SQL:    "SELECT * FROM users WHERE name = 'it''s' OR name = 'a\\'b'",
+			Result: "SELECT * FROM users WHERE name = ? OR name = ?",
+		},
+		{
+			SQL:    "SELECT `id`, `name` FROM `users1` WHERE (id IN (1, 2, 3)) LIMIT 10",
+			Result: "SELECT `id`, `name` FROM `users1` WHERE (id IN (?)) LIMIT ?",
+		},
+		{
+			SQL:    "SELECT id FROM users WHERE id in (?, ?, ?) AND role NOT IN ('a','b')",
+			Result: "SELECT id FROM users WHERE id in (?) AND role NOT IN (?)",
+		},
+		{
+			SQL:    "SELECT id FROM users WHERE id IN (SELECT user_id FROM orders)",
+			Result: "SELECT id FROM users WHERE id IN (SELECT user_id FROM orders)",
+		},
+		{
+			SQL:    "INSERT INTO users (name, score, token, flag) VALUES ('foo', -1.5e3, 0xDEADBEEF, X'0F')",
+			Result: "INSERT INTO users (name, score, token, flag) VALUES (?, -?, ?, ?)",
+		},
+		{
+			SQL:    `SELECT "name" FROM "users" WHERE id = $1 AND body = $$it's secret$$ AND note = $tag$a $$ b$tag$`,
+			Result: `SELECT ? FROM ? WHERE id = $1 AND body = ? AND note = ?`,
+		},
+		{
+			SQL:    "SELECT id /* token=abc */ FROM users -- email = 'w@g.com'\nWHERE name = :name AND id = @p1 AND created::date = '2024-01-01'",
+			Result: "SELECT id  FROM users \nWHERE name = :name AND id = @p1 AND created::date = ?",
+		},
+		{
+			SQL:    "SELECT id FROM users# mysql comment\nWHERE secret = N'pass'",
+			Result: "SELECT id FROM users\nWHERE secret = ?",
+		},
+		{
+			SQL:    "SELECT 1/*c*/FROM dual",
+			Result: "SELECT ? FROM dual",
+		},
+		{
+			SQL:    "SELECT 'unterminated",
+			Result: "SELECT ?",
+		},
+		{
+			SQL:    "SELECT id FROM files WHERE p = 'C:\\' AND token = 'abc123'",
+			Result: "SELECT id FROM files WHERE p = ?",
+		},
+		{
+			SQL:    `SELECT id FROM users WHERE email = "bob@example.com"`,
+			Result: "SELECT id FROM users WHERE email = ?",
+		},
+		{
+			SQL:    `SELECT id FROM users WHERE name = "C:\" AND token = "abc123"`,
+			Result: "SELECT id FROM users WHERE name = ?",
+		},
+	}
+
+	for idx, r := range results {
+		r := r
+		t.Run(fmt.Sprintf("#%v", idx), func(t *testing.T) {
+			if result := ObfuscateSQL(r.SQL); result != r.Result {
+				t.Errorf("obfuscate SQL #%v\nexpected\n%v\nbut got\n%v", idx, r.Result, result)
+			}
+		})
+	}
+}
+
+func TestDialectObfuscateSQL(t *testing.T) {
+	results := []struct {
+		System string
+		SQL    string
+		Result string
+	}{
+		{
+			System: "postgresql",
+			SQL:    "SELECT id FROM files WHERE p = 'C:\\' AND token = 'abc123'",
+			Result: "SELECT id FROM files WHERE p = ? AND token = ?",
+		},
+		{
+			System: "postgresql",
+			SQL:    "SELECT id FROM files WHERE p = E'it\\'s' AND token = 'abc123'",
+			Result: "SELECT id FROM files WHERE p = ? AND token = ?",
+		},
+		{
+			System: "sqlite",
+			SQL:    "SELECT id FROM files WHERE p = 'C:\\' AND token = 'abc123'",
+			Result: "SELECT id FROM files WHERE p = ? AND token = ?",
+		},
+		{
+			System: "mysql",
+			SQL:    "SELECT id FROM users WHERE name = 'it\\'s secret' AND token = 'abc123'",
+			Result: "SELECT id FROM users WHERE name = ? AND token = ?",
+		},
+		{
+			System: "mysql",
+			SQL:    `SELECT id FROM users WHERE email = "bob@example.com" AND name = "it\"s" AND token = 'abc123'`,
+			Result: "SELECT id FROM users WHERE email = ? AND name = ? AND token = ?",
+		},
+		{
+			System: "tidb",
+			SQL:    `SELECT id FROM users WHERE email IN ("a@example.com", "b@example.com")`,
+			Result: "SELECT id FROM users WHERE email IN (?)",
+		},
+		{
+			System: "postgresql",
+			SQL:    `SELECT "name" FROM "users" WHERE email = 'bob@example.com'`,
+			Result: `SELECT "name" FROM "users" WHERE email = ?`,
+		},
+	}
+
+	for idx, r := range results {
+		r := r
+		t.Run(fmt.Sprintf("#%v", idx), func(t *testing.T) {
+			if result := DialectOf(r.System).ObfuscateSQL(r.SQL); result != r.Result {
+				t.Errorf("obfuscate %v SQL #%v\nexpected\n%v\nbut got\n%v", r.System, idx, r.Result, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const redacted = "<redacted>"
+
+var (
+	secretType      = reflect.TypeOf(Secret(""))
+	insertColsRegex = regexp.MustCompile("(?is)^\\s*(?:insert|replace)\\s+(?:into\\s+)?[^(\\s]+\\s*\\(([^)]*)\\)\\s*values")
+)
+
+// Secret marks a parameter which is always redacted by ExplainSQLRedacted.
+type Secret string
+
+// Value implements driver.Valuer, the database receives the plain string.
+func (s Secret) Value() (driver.Value, error) {
+	return string(s), nil
+}
+
+// RedactionPolicy selects the parameters replaced with '<redacted>' by ExplainSQLRedacted,
+// a parameter is redacted when any of the rules matches it.
+type RedactionPolicy struct {
+	// Positions are the zero-based positions of the parameters.
+	Positions []int
+	// Types are the Go types of the parameters, Secret is always redacted.
+	Types []reflect.Type
+	// Columns are the column names compared, case-insensitively, with the column of the parameter
+	// matched from the statement, e.g. `email = ?`, `email IN (?, ?)` or `INSERT INTO t (email) VALUES (?)`.
+	Columns []string
+	// Values are the patterns of the parameter values, the quotes excluded.
+	Values []*regexp.Regexp
+}
+
+func (p *RedactionPolicy) redact(query string, numericPlaceholder *regexp.Regexp, q quoting, escaper string, avars []any, vars []string) {
+	var columns [][]string
+	if len(p.Columns) > 0 {
+		columns = placeholderColumns(query, numericPlaceholder, q, avars)
+	}
+
+	for idx, v := range avars {
+		if named, ok := v.(sql.NamedArg); ok {
+			v = named.Value
+		}
+		if p.match(idx, v, columns, unquote(vars[idx], escaper)) {
+			vars[idx] = escaper + redacted + escaper
+		}
+	}
+}
+
+func (p *RedactionPolicy) match(idx int, v any, columns [][]string, value string) bool {
+	for _, pos := range p.Positions {
+		if pos == idx {
+			return true
+		}
+	}
+
+	t := reflect.TypeOf(v)
+	if t == secretType {
+		return true
+	}
+	for _, typ := range p.Types {
+		if t == typ {
+			return true
+		}
+	}
+
+	if idx < len(columns) {
+		for _, column := range columns[idx] {
+			for _, col := range p.Columns {
+				if strings.EqualFold(col, column) {
+					return true
+				}
+			}
+		}
+	}
+
+	for _, re := range p.Values {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func unquote(v, escaper string) string {
+	if escaper != "" && len(v) >= 2*len(escaper) && strings.HasPrefix(v, escaper) && strings.HasSuffix(v, escaper) {
+		return strings.ReplaceAll(v[len(escaper):len(v)-len(escaper)], escaper+escaper, escaper)
+	}
+	return v
+}
+
+// placeholderColumns returns the column names of each of the parameters, none when unknown: the `?` placeholders
+// take the next unnamed parameter, the numeric ones their position and the `@name`, `:name` and `$name` ones
+// the sql.NamedArg of their name, as ExplainSQL replaces them.
+func placeholderColumns(query string, numericPlaceholder *regexp.Regexp, q quoting, avars []any) [][]string {
+	columns := make([][]string, len(avars))
+
+	names := make([]string, len(avars))
+	for idx, v := range avars {
+		if named, ok := v.(sql.NamedArg); ok {
+			names[idx] = named.Name
+		}
+	}
+
+	var insertCols []string
+	if m := insertColsRegex.FindStringSubmatch(query); m != nil {
+		for _, col := range strings.Split(m[1], ",") {
+			insertCols = append(insertCols, strings.Trim(strings.TrimSpace(col), "`\"[]"))
+		}
+	}
+
+	tokens := tokenize(query, q)
+
+	var seq, pos int
+	for i, t := range tokens {
+		if t.kind != tokenPlaceholder {
+			continue
+		}
+
+		idx := -1
+		switch {
+		case t.text == "?":
+			for seq < len(avars) && names[seq] != "" {
+				seq++
+			}
+			idx = seq
+			seq++
+		case numericPlaceholder != nil && isNumericPlaceholder(numericPlaceholder, t.text):
+			m := numericPlaceholder.FindStringSubmatch(t.text)
+			if num, err := strconv.Atoi(m[1]); err == nil {
+				idx = num - 1
+			}
+		default:
+			for n, name := range names {
+				if name != "" && name == t.text[1:] {
+					idx = n
+					break
+				}
+			}
+		}
+		pos++
+
+		if idx < 0 || idx >= len(avars) {
+			continue
+		}
+
+		var column string
+		if len(insertCols) > 0 {
+			column = insertCols[(pos-1)%len(insertCols)]
+		} else {
+			column = precedingColumn(tokens, i)
+		}
+		if column != "" {
+			columns[idx] = append(columns[idx], column)
+		}
+	}
+
+	return columns
+}
+
+// isNumericPlaceholder reports whether the whole placeholder matches the numericPlaceholder.
+func isNumericPlaceholder(numericPlaceholder *regexp.Regexp, placeholder string) bool {
+	m := numericPlaceholder.FindStringSubmatch(placeholder)
+	return len(m) > 1 && m[0] == placeholder
+}
+
+// precedingColumn returns the column compared with the placeholder at i, e.g. `email` of `t.email LIKE ?`.
+func precedingColumn(tokens []token, i int) string {
+	for i--; i >= 0; i-- {
+		t := tokens[i]
+		switch t.kind {
+		case tokenSpace, tokenComment, tokenLiteral, tokenPlaceholder:
+			continue
+		case tokenPunct:
+			if strings.Contains("=<>!(,", t.text) {
+				continue
+			}
+			return ""
+		case tokenWord:
+			switch strings.ToLower(t.text) {
+			case "like", "ilike", "in", "not", "is", "between", "and":
+				continue
+			}
+			return t.text
+		case tokenIdentifier:
+			return t.text[1 : len(t.text)-1]
+		}
+	}
+	return ""
+}
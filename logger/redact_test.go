@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestExplainSQLRedacted(t *testing.T) {
+	type token string
+
+	results := []struct {
+		SQL           string
+		NumericRegexp *regexp.Regexp
+		Policy        *RedactionPolicy
+		Vars          []any
+		Result        string
+	}{
+		{
+			SQL:    "SELECT * FROM users WHERE name = ? AND pass = ?",
+			Policy: &RedactionPolicy{Positions: []int{1}},
+			Vars:   []any{"foo", "bar"},
+			Result: `SELECT * FROM users WHERE name = 'foo' AND pass = '<redacted>'`,
+		},
+		{
+			SQL:    "SELECT * FROM users WHERE name = ? AND pass = ? AND token = ?",
+			Policy: &RedactionPolicy{Types: []reflect.Type{reflect.TypeOf(token(""))}},
+			Vars:   []any{"foo", Secret("bar"), token("baz")},
+			Result: `SELECT * FROM users WHERE name = 'foo' AND pass = '<redacted>' AND token = '<redacted>'`,
+		},
+		{
+			SQL:    "SELECT * FROM `users` WHERE `users`.`email` IN (?, ?) AND age BETWEEN ? AND ? AND phone LIKE ?",
+			Policy: &RedactionPolicy{Columns: []string{"EMAIL", "phone"}},
+			Vars:   []any{"a@g.com", "b@g.com", 18, 30, "+86%"},
+			Result: "SELECT * FROM `users` WHERE `users`.`email` IN ('<redacted>', '<redacted>') AND age BETWEEN 18 AND 30 AND phone LIKE '<redacted>'",
+		},
+		{
+			SQL:    "INSERT INTO `users` (`name`, `email`) VALUES (?, ?), (?, ?)",
+			Policy: &RedactionPolicy{Columns: []string{"email"}},
+			Vars:   []any{"foo", "a@g.com", "bar", "b@g.com"},
+			Result: "INSERT INTO `users` (`name`, `email`) VALUES ('foo', '<redacted>'), ('bar', '<redacted>')",
+		},
+		{
+			SQL:           "UPDATE users SET email = $2 WHERE id = $1",
+			NumericRegexp: regexp.MustCompile(`\$(\d+)`),
+			Policy:        &RedactionPolicy{Columns: []string{"email"}},
+			Vars:          []any{1, "a@g.com"},
+			Result:        "UPDATE users SET email = '<redacted>' WHERE id = 1",
+		},
+		{
+			SQL:    "SELECT * FROM users WHERE note = ? AND name = ?",
+			Policy: &RedactionPolicy{Values: []*regexp.Regexp{regexp.MustCompile(`^\S+@\S+$`)}},
+			Vars:   []any{"mail me at a@g.com", "a@g.com"},
+			Result: `SELECT * FROM users WHERE note = 'mail me at a@g.com' AND name = '<redacted>'`,
+		},
+		{
+			SQL:    "SELECT * FROM users WHERE name = :name AND pass = :pass AND token = :token",
+			Policy: &RedactionPolicy{Types: []reflect.Type{reflect.TypeOf(token(""))}},
+			Vars:   []any{sql.Named("name", "foo"), sql.Named("pass", Secret("bar")), sql.Named("token", token("baz"))},
+			Result: `SELECT * FROM users WHERE name = 'foo' AND pass = '<redacted>' AND token = '<redacted>'`,
+		},
+		{
+			SQL:    "SELECT * FROM users WHERE name = ?",
+			Policy: nil,
+			Vars:   []any{"foo"},
+			Result: `SELECT * FROM users WHERE name = 'foo'`,
+		},
+	}
+
+	for idx, r := range results {
+		r := r
+		t.Run(fmt.Sprintf("#%v", idx), func(t *testing.T) {
+			if result := ExplainSQLRedacted(r.SQL, r.NumericRegexp, `'`, r.Policy, r.Vars...); result != r.Result {
+				t.Errorf("explain SQL #%v\nexpected\n%v\nbut got\n%v", idx, r.Result, result)
+			}
+		})
+	}
+}
+
+func TestDialectExplainSQLRedacted(t *testing.T) {
+	policy := &RedactionPolicy{Columns: []string{"email"}}
+
+	results := []struct {
+		SQL     string
+		Dialect Dialect
+		Vars    []any
+		Result  string
+	}{
+		{
+			SQL:     "SELECT * FROM u WHERE email = @email",
+			Dialect: DialectOf("mssql"),
+			Vars:    []any{sql.Named("email", "a@b.c")},
+			Result:  `SELECT * FROM u WHERE email = '<redacted>'`,
+		},
+		{
+			SQL:     "SELECT * FROM u WHERE id = :id AND email = :email",
+			Dialect: DialectOf("oracle"),
+			Vars:    []any{sql.Named("email", "a@b.c"), sql.Named("id", 1)},
+			Result:  `SELECT * FROM u WHERE id = 1 AND email = '<redacted>'`,
+		},
+		{
+			SQL:     "SELECT * FROM u WHERE name = $name OR email = $name AND id = ?",
+			Dialect: DialectOf("sqlite"),
+			Vars:    []any{sql.Named("name", "a@b.c"), 1},
+			Result:  `SELECT * FROM u WHERE name = '<redacted>' OR email = '<redacted>' AND id = 1`,
+		},
+		{
+			SQL:     "INSERT INTO u (name, email) VALUES (@name, @email)",
+			Dialect: DialectOf("mssql"),
+			Vars:    []any{sql.Named("email", "a@b.c"), sql.Named("name", "foo")},
+			Result:  `INSERT INTO u (name, email) VALUES ('foo', '<redacted>')`,
+		},
+	}
+
+	for idx, r := range results {
+		r := r
+		t.Run(fmt.Sprintf("#%v", idx), func(t *testing.T) {
+			if result := r.Dialect.ExplainSQL(r.SQL, policy, r.Vars...); result != r.Result {
+				t.Errorf("explain SQL #%v\nexpected\n%v\nbut got\n%v", idx, r.Result, result)
+			}
+		})
+	}
+}
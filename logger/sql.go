@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"database/sql"
 	"database/sql/driver"
 	"fmt"
 	"reflect"
@@ -20,11 +21,35 @@ const (
 // ExplainSQL generate SQL string with given parameters, the generated SQL is expected to
 // be used in logger, because executing it might introduce a SQL injection.
 func ExplainSQL(sql string, numericPlaceholder *regexp.Regexp, escaper string, avars ...any) string {
+	return ExplainSQLRedacted(sql, numericPlaceholder, escaper, nil, avars...)
+}
+
+// ExplainSQLRedacted is ExplainSQL replacing the parameters selected by the policy with '<redacted>'.
+func ExplainSQLRedacted(sql string, numericPlaceholder *regexp.Regexp, escaper string, policy *RedactionPolicy, avars ...any) string {
+	return Dialect{NumericPlaceholder: numericPlaceholder, Escaper: escaper}.ExplainSQL(sql, policy, avars...)
+}
+
+// ExplainSQL generate SQL string with given parameters in the placeholder style and the string escaping of the dialect,
+// the parameters selected by the policy, if any, are replaced with '<redacted>'.
+// The placeholders inside quoted strings and comments are left untouched, the strings being read with
+// the backslash escapes of the dialect, sql.NamedArg parameters replace the `@name`, `:name` and `$name` placeholders.
+// A NumericPlaceholder other than DollarPlaceholder, AtPPlaceholder and ColonPlaceholder replaces
+// every one of its matches, as ExplainSQL always did.
+func (d Dialect) ExplainSQL(query string, policy *RedactionPolicy, avars ...any) string {
 	var (
 		convertParams func(any, int)
 		vars          = make([]string, len(avars))
+		names         = make([]string, len(avars))
+		escaper       = d.Escaper
 	)
 
+	quote := func(s string) string {
+		if d.EscapeBackslash {
+			s = strings.ReplaceAll(s, `\`, `\\`)
+		}
+		return escaper + strings.ReplaceAll(s, escaper, escaper+escaper) + escaper
+	}
+
 	convertParams = func(v any, idx int) {
 		switch v := v.(type) {
 		case bool:
@@ -63,17 +88,17 @@ func ExplainSQL(sql string, numericPlaceholder *regexp.Regexp, escaper string, a
 			case reflect.Bool:
 				vars[idx] = fmt.Sprintf("%t", reflectValue.Interface())
 			case reflect.String:
-				vars[idx] = escaper + strings.ReplaceAll(fmt.Sprintf("%v", v), escaper, escaper+escaper) + escaper
+				vars[idx] = quote(fmt.Sprintf("%v", v))
 			default:
 				if v != nil && reflectValue.IsValid() && ((reflectValue.Kind() == reflect.Ptr && !reflectValue.IsNil()) || reflectValue.Kind() != reflect.Ptr) {
-					vars[idx] = escaper + strings.ReplaceAll(fmt.Sprintf("%v", v), escaper, escaper+escaper) + escaper
+					vars[idx] = quote(fmt.Sprintf("%v", v))
 				} else {
 					vars[idx] = nullStr
 				}
 			}
 		case []byte:
 			if s := string(v); isPrintable(s) {
-				vars[idx] = escaper + strings.ReplaceAll(s, escaper, escaper+escaper) + escaper
+				vars[idx] = quote(s)
 			} else {
 				vars[idx] = escaper + "<binary>" + escaper
 			}
@@ -84,7 +109,7 @@ func ExplainSQL(sql string, numericPlaceholder *regexp.Regexp, escaper string, a
 		case float64:
 			vars[idx] = strconv.FormatFloat(v, 'f', -1, 64)
 		case string:
-			vars[idx] = escaper + strings.ReplaceAll(v, escaper, escaper+escaper) + escaper
+			vars[idx] = quote(v)
 		default:
 			rv := reflect.ValueOf(v)
 			if v == nil || !rv.IsValid() || rv.Kind() == reflect.Ptr && rv.IsNil() {
@@ -107,48 +132,103 @@ func ExplainSQL(sql string, numericPlaceholder *regexp.Regexp, escaper string, a
 						return
 					}
 				}
-				vars[idx] = escaper + strings.ReplaceAll(fmt.Sprint(v), escaper, escaper+escaper) + escaper
+				vars[idx] = quote(fmt.Sprint(v))
 			}
 		}
 	}
 
 	for idx, v := range avars {
+		if named, ok := v.(sql.NamedArg); ok {
+			names[idx] = named.Name
+			v = named.Value
+		}
 		convertParams(v, idx)
 	}
 
-	if numericPlaceholder == nil {
-		var idx int
-		var newSQL strings.Builder
+	if policy != nil {
+		policy.redact(query, d.NumericPlaceholder, d.quoting(), escaper, avars, vars)
+	}
 
-		for _, v := range []byte(sql) {
-			if v == '?' {
-				if len(vars) > idx {
-					newSQL.WriteString(vars[idx])
-					idx++
-					continue
-				}
+	if d.NumericPlaceholder != nil && !isLexedPlaceholder(d.NumericPlaceholder) {
+		return explainNumeric(query, d.NumericPlaceholder, vars)
+	}
+
+	var (
+		idx    int
+		newSQL strings.Builder
+	)
+	for _, t := range tokenize(query, d.quoting()) {
+		if t.kind == tokenPlaceholder {
+			if v, ok := d.placeholderVar(t.text, vars, names, &idx); ok {
+				newSQL.WriteString(v)
+				continue
 			}
-			newSQL.WriteByte(v)
 		}
+		newSQL.WriteString(t.text)
+	}
+
+	return newSQL.String()
+}
 
-		sql = newSQL.String()
-	} else {
-		sql = numericPlaceholder.ReplaceAllString(sql, "$$$1$$")
+// isLexedPlaceholder reports whether the placeholders of the pattern are tokens of the lexer,
+// the placeholders of the other patterns are replaced by explainNumeric.
+func isLexedPlaceholder(numericPlaceholder *regexp.Regexp) bool {
+	switch numericPlaceholder.String() {
+	case DollarPlaceholder.String(), AtPPlaceholder.String(), ColonPlaceholder.String():
+		return true
+	default:
+		return false
+	}
+}
 
-		sql = numericPlaceholderRe.ReplaceAllStringFunc(sql, func(v string) string {
-			num := v[1 : len(v)-1]
-			n, _ := strconv.Atoi(num)
+// explainNumeric replaces every match of the numericPlaceholder with its parameter, the quoted strings included.
+func explainNumeric(query string, numericPlaceholder *regexp.Regexp, vars []string) string {
+	query = numericPlaceholder.ReplaceAllString(query, "$$$1$$")
 
+	return numericPlaceholderRe.ReplaceAllStringFunc(query, func(v string) string {
+		num := v[1 : len(v)-1]
+		n, _ := strconv.Atoi(num)
+
+		// position var start from 1 ($1, $2)
+		n -= 1
+		if n >= 0 && n <= len(vars)-1 {
+			return vars[n]
+		}
+		return v
+	})
+}
+
+// placeholderVar returns the parameter of the placeholder, idx is the next `?` parameter.
+func (d Dialect) placeholderVar(placeholder string, vars, names []string, idx *int) (string, bool) {
+	if placeholder == "?" {
+		for *idx < len(vars) && names[*idx] != "" {
+			*idx++
+		}
+		if *idx < len(vars) {
+			*idx++
+			return vars[*idx-1], true
+		}
+		return "", false
+	}
+
+	if d.NumericPlaceholder != nil {
+		if m := d.NumericPlaceholder.FindStringSubmatch(placeholder); len(m) > 1 && m[0] == placeholder {
 			// position var start from 1 ($1, $2)
+			n, _ := strconv.Atoi(m[1])
 			n -= 1
 			if n >= 0 && n <= len(vars)-1 {
-				return vars[n]
+				return vars[n], true
 			}
-			return v
-		})
+			return "", false
+		}
 	}
 
-	return sql
+	for i, name := range names {
+		if name != "" && name == placeholder[1:] {
+			return vars[i], true
+		}
+	}
+	return "", false
 }
 
 // a list of Go types that should be converted to SQL primitives.
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
@@ -139,6 +140,12 @@ func TestExplainSQ(t *testing.T) {
 			Vars:          []interface{}{"test?", 1, 999.99, true, []byte("12345"), tt, &tt, nil, "w@g.\"com", myRole, pwd, floatVal},
 			Result:        `create table users (name, age, height, actived, bytes, create_at, update_at, deleted_at, email, role, pass, float_val) values ("test?", 1, 999.99, true, "12345", "2024-09-26 17:45:15", "2024-09-26 17:45:15", NULL, "w@g.""com", "admin", "pass", 1.230000)`,
 		},
+		{
+			SQL:           "select * from users where a = ?1 and b = ?2",
+			NumericRegexp: regexp.MustCompile(`\?(\d+)`),
+			Vars:          []interface{}{1, 2},
+			Result:        `select * from users where a = 1 and b = 2`,
+		},
 	}
 
 	for idx, r := range results {
@@ -155,3 +162,70 @@ func TestExplainSQ(t *testing.T) {
 		})
 	}
 }
+
+func TestDialectExplainSQL(t *testing.T) {
+	results := []struct {
+		SQL     string
+		Dialect Dialect
+		Vars    []any
+		Result  string
+	}{
+		{
+			SQL:     "SELECT * FROM users WHERE name = ? AND note = 'why?' AND id = ?",
+			Dialect: DialectOf("sqlite"),
+			Vars:    []any{"foo", 1},
+			Result:  `SELECT * FROM users WHERE name = 'foo' AND note = 'why?' AND id = 1`,
+		},
+		{
+			SQL:     "SELECT * FROM users WHERE name = ? /* ? */ AND path = ?",
+			Dialect: DialectOf("mysql"),
+			Vars:    []any{"it's", `C:\dir`},
+			Result:  `SELECT * FROM users WHERE name = 'it''s' /* ? */ AND path = 'C:\\dir'`,
+		},
+		{
+			SQL:     "SELECT * FROM users WHERE name = $2 AND note = '$1' AND id = $1",
+			Dialect: DialectOf("postgresql"),
+			Vars:    []any{1, "foo"},
+			Result:  `SELECT * FROM users WHERE name = 'foo' AND note = '$1' AND id = 1`,
+		},
+		{
+			SQL:     "SELECT * FROM users WHERE name = @p2 AND id = @p1",
+			Dialect: DialectOf("mssql"),
+			Vars:    []any{1, "foo"},
+			Result:  `SELECT * FROM users WHERE name = 'foo' AND id = 1`,
+		},
+		{
+			SQL:     "SELECT * FROM users WHERE name = :2 AND id = :1 AND role = :role",
+			Dialect: DialectOf("oracle"),
+			Vars:    []any{1, "foo", sql.Named("role", "admin")},
+			Result:  `SELECT * FROM users WHERE name = 'foo' AND id = 1 AND role = 'admin'`,
+		},
+		{
+			SQL:     "SELECT * FROM users WHERE name = @name AND id = ? AND role = $role",
+			Dialect: DialectOf("sqlite"),
+			Vars:    []any{sql.Named("name", "foo"), 1, sql.Named("role", "admin")},
+			Result:  `SELECT * FROM users WHERE name = 'foo' AND id = 1 AND role = 'admin'`,
+		},
+		{
+			SQL:     `SELECT * FROM files WHERE p = 'C:\' AND id = $1`,
+			Dialect: DialectOf("postgresql"),
+			Vars:    []any{7},
+			Result:  `SELECT * FROM files WHERE p = 'C:\' AND id = 7`,
+		},
+		{
+			SQL:     `SELECT * FROM users WHERE note = 'it\'s ?' AND id = ?`,
+			Dialect: DialectOf("mysql"),
+			Vars:    []any{7},
+			Result:  `SELECT * FROM users WHERE note = 'it\'s ?' AND id = 7`,
+		},
+	}
+
+	for idx, r := range results {
+		r := r
+		t.Run(fmt.Sprintf("#%v", idx), func(t *testing.T) {
+			if result := r.Dialect.ExplainSQL(r.SQL, nil, r.Vars...); result != r.Result {
+				t.Errorf("explain SQL #%v\nexpected\n%v\nbut got\n%v", idx, r.Result, result)
+			}
+		})
+	}
+}
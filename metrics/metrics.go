@@ -3,93 +3,216 @@ package metrics
 import (
 	"context"
 	"database/sql"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 )
 
-const instrumentName = "go.opentelemetry.io/otel"
+const (
+	instrumentName = "go.opentelemetry.io/otel"                      // the meter of the legacy names
+	scopeName      = "github.com/dapings/opentelemetry-xorm/metrics" // the meter of the semantic convention names
+)
 
 type config struct {
-	tracerProvider trace.TracerProvider
-	tracer         trace.Tracer
-
 	meterProvider metric.MeterProvider
 	meter         metric.Meter
 
-	opts []metric.ObserveOption
+	attrs       []attribute.KeyValue
+	opts        []metric.ObserveOption
+	legacyNames bool
 }
 
-func newConfig() *config {
+func newConfig(opts ...Option) *config {
 	c := &config{
-		tracerProvider: otel.GetTracerProvider(),
-		tracer:         nil,
-		meterProvider:  otel.GetMeterProvider(),
-		meter:          nil,
-		opts:           nil,
+		meterProvider: otel.GetMeterProvider(),
+		meter:         nil,
+		opts:          nil,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if len(c.attrs) > 0 {
+		c.opts = append(c.opts, metric.WithAttributes(c.attrs...))
 	}
 	return c
 }
 
-// ReportDBStatsMetrics reports DBStats metrics using OpenTelemetry Metrics API.
-func ReportDBStatsMetrics(db *sql.DB) {
-	cfg := newConfig()
+// Option configures the DBStats metrics.
+type Option func(c *config)
+
+// WithMeterProvider configures a meter provider that is used to create the instruments, the global one by default.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		if provider != nil {
+			c.meterProvider = provider
+		}
+	}
+}
+
+// WithPoolName configures a pool.name attribute, which tells the pools apart.
+func WithPoolName(name string) Option {
+	return WithAttributes(poolName.String(name))
+}
+
+// WithDBSystem configures a db.system attribute.
+func WithDBSystem(system string) Option {
+	return WithAttributes(semconv.DBSystemKey.String(system))
+}
+
+// WithDBName configures a db.name attribute.
+func WithDBName(name string) Option {
+	return WithAttributes(semconv.DBNameKey.String(name))
+}
+
+// WithLegacyNames keeps the go.sql.* names, units and meter of the previous releases, for the existing dashboards.
+func WithLegacyNames() Option {
+	return func(c *config) {
+		c.legacyNames = true
+	}
+}
+
+// WithAttributes configures attributes that are added to every observation.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attrs = append(c.attrs, attrs...)
+	}
+}
+
+// Registration is the registration of the DBStats metrics of a pool.
+type Registration struct {
+	reg      metric.Registration
+	waitTime metric.Float64Histogram // nil with the legacy names
+	timeouts metric.Int64Counter     // nil with the legacy names
+	opt      metric.MeasurementOption
+}
+
+// Unregister stops reporting the DBStats metrics of the pool.
+func (r *Registration) Unregister() error {
+	return r.reg.Unregister()
+}
+
+// ReportDBStatsMetrics reports DBStats metrics with the legacy go.sql.* names using OpenTelemetry Metrics API,
+// the attrs are added to every observation, e.g. the role of an engine group member.
+// It panics if the metrics cannot be registered.
+//
+// Deprecated: use RegisterDBStatsMetrics, which returns the registration errors.
+func ReportDBStatsMetrics(db *sql.DB, attrs ...attribute.KeyValue) {
+	if _, err := RegisterDBStatsMetrics(db, WithAttributes(attrs...), WithLegacyNames()); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterDBStatsMetrics reports the DBStats metrics of the pool until the registration is unregistered,
+// with the db.client.connections.* semantic convention names, or the go.sql.* ones with WithLegacyNames.
+func RegisterDBStatsMetrics(db *sql.DB, opts ...Option) (*Registration, error) {
+	cfg := newConfig(opts...)
+
+	if !cfg.legacyNames {
+		return registerDBStats(cfg.meterProvider.Meter(scopeName), db, cfg.attrs)
+	}
 
 	if cfg.meter == nil {
 		cfg.meter = cfg.meterProvider.Meter(instrumentName)
 	}
 
-	meter := cfg.meter
-	opts := cfg.opts
+	reg, err := registerLegacyDBStats(cfg.meter, db, cfg.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Registration{reg: reg}, nil
+}
 
-	maxOpenConns, _ := meter.Int64ObservableGauge(
+// registerLegacyDBStats registers the go.sql.* DBStats metrics.
+func registerLegacyDBStats(meter metric.Meter, db *sql.DB, observeOpts []metric.ObserveOption) (metric.Registration, error) {
+	maxOpenConns, err := meter.Int64ObservableGauge(
 		"go.sql.connections_max_open",
 		metric.WithDescription("Maximum number of open connections to the database"),
 	)
-	openConns, _ := meter.Int64ObservableGauge(
+	if err != nil {
+		return nil, err
+	}
+
+	openConns, err := meter.Int64ObservableGauge(
 		"go.sql.connections_open",
 		metric.WithDescription("The number of established connections both in use and idle"),
 	)
-	inUseConns, _ := meter.Int64ObservableGauge(
+	if err != nil {
+		return nil, err
+	}
+
+	inUseConns, err := meter.Int64ObservableGauge(
 		"go.sql.connections_in_use",
 		metric.WithDescription("The number of connections currently in use"),
 	)
-	idleConns, _ := meter.Int64ObservableGauge(
+	if err != nil {
+		return nil, err
+	}
+
+	idleConns, err := meter.Int64ObservableGauge(
 		"go.sql.connections_idle",
 		metric.WithDescription("The number of idle connections"),
 	)
-	connsWaitCount, _ := meter.Int64ObservableCounter(
+	if err != nil {
+		return nil, err
+	}
+
+	connsWaitCount, err := meter.Int64ObservableCounter(
 		"go.sql.connections_wait_count",
 		metric.WithDescription("The total number of connections waited for"),
 	)
-	connsWaitDuration, _ := meter.Int64ObservableCounter(
+	if err != nil {
+		return nil, err
+	}
+
+	connsWaitDuration, err := meter.Int64ObservableCounter(
 		"go.sql.connections_wait_duration",
 		metric.WithDescription("The total time blocked waiting for a new connection"),
 		metric.WithUnit("nanoseconds"),
 	)
-	connsClosedMaxIdle, _ := meter.Int64ObservableCounter(
+	if err != nil {
+		return nil, err
+	}
+
+	connsClosedMaxIdle, err := meter.Int64ObservableCounter(
 		"go.sql.connections_closed_max_idle",
 		metric.WithDescription("The total number of connections closed due to SetMaxIdleConns"),
 	)
-	connsClosedMaxIdleTime, _ := meter.Int64ObservableCounter(
+	if err != nil {
+		return nil, err
+	}
+
+	connsClosedMaxIdleTime, err := meter.Int64ObservableCounter(
 		"go.sql.connections_closed_max_idle_time",
 		metric.WithDescription("The total number of connections closed due to SetConnMaxIdleTime"),
 	)
-	connsClosedMaxLifetime, _ := meter.Int64ObservableCounter(
+	if err != nil {
+		return nil, err
+	}
+
+	connsClosedMaxLifetime, err := meter.Int64ObservableCounter(
 		"go.sql.connections_closed_max_lifetime",
 		metric.WithDescription("The total number of connections closed due to SetConnMaxLifetime"),
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	_, err := meter.RegisterCallback(
+	reg, err := meter.RegisterCallback(
 		func(ctx context.Context, o metric.Observer) error {
 			stats := db.Stats()
 
-			o.ObserveInt64(maxOpenConns, int64(stats.MaxOpenConnections), opts...)
-			o.ObserveInt64(openConns, int64(stats.OpenConnections), opts...)
-			o.ObserveInt64(inUseConns, int64(stats.InUse), opts...)
-			o.ObserveInt64(idleConns, int64(stats.Idle), opts...)
-			o.ObserveInt64(connsWaitCount, stats.WaitCount, opts...)
-			o.ObserveInt64(connsWaitDuration, int64(stats.WaitDuration), opts...)
-			o.ObserveInt64(connsClosedMaxIdle, stats.MaxIdleClosed, opts...)
-			o.ObserveInt64(connsClosedMaxIdleTime, stats.MaxIdleTimeClosed, opts...)
-			o.ObserveInt64(connsClosedMaxLifetime, stats.MaxLifetimeClosed, opts...)
+			o.ObserveInt64(maxOpenConns, int64(stats.MaxOpenConnections), observeOpts...)
+			o.ObserveInt64(openConns, int64(stats.OpenConnections), observeOpts...)
+			o.ObserveInt64(inUseConns, int64(stats.InUse), observeOpts...)
+			o.ObserveInt64(idleConns, int64(stats.Idle), observeOpts...)
+			o.ObserveInt64(connsWaitCount, stats.WaitCount, observeOpts...)
+			o.ObserveInt64(connsWaitDuration, int64(stats.WaitDuration), observeOpts...)
+			o.ObserveInt64(connsClosedMaxIdle, stats.MaxIdleClosed, observeOpts...)
+			o.ObserveInt64(connsClosedMaxIdleTime, stats.MaxIdleTimeClosed, observeOpts...)
+			o.ObserveInt64(connsClosedMaxLifetime, stats.MaxLifetimeClosed, observeOpts...)
 			return nil
 		},
 		maxOpenConns,
@@ -104,6 +227,8 @@ func ReportDBStatsMetrics(db *sql.DB) {
 	)
 
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+
+	return reg, nil
 }
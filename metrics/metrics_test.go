@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRegisterDBStatsMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []Option
+		scope  string
+		metric string
+	}{
+		{name: "semconv", scope: scopeName, metric: "db.client.connections.max"},
+		{name: "legacy", opts: []Option{WithLegacyNames()}, scope: instrumentName, metric: "go.sql.connections_max_open"},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+			var regs []*Registration
+			for _, name := range []string{"primary", "replica"} {
+				db, err := sql.Open("sqlite3", "file:"+name+"?mode=memory&cache=shared")
+				require.NoError(t, err)
+				defer db.Close()
+
+				opts := append([]Option{WithMeterProvider(meterProvider), WithPoolName(name),
+					WithDBSystem("sqlite3"), WithDBName(name)}, test.opts...)
+				reg, err := RegisterDBStatsMetrics(db, opts...)
+				require.NoError(t, err)
+				regs = append(regs, reg)
+			}
+
+			var rm metricdata.ResourceMetrics
+			require.NoError(t, reader.Collect(context.TODO(), &rm))
+			require.Equal(t, 1, len(rm.ScopeMetrics))
+			require.Equal(t, test.scope, rm.ScopeMetrics[0].Scope.Name)
+
+			got := metricAttributes(rm, test.metric)
+			require.Equal(t, 2, len(got))
+			for _, attrs := range got {
+				name, ok := attrs.Value(poolName)
+				require.True(t, ok)
+				dbName, ok := attrs.Value("db.name")
+				require.True(t, ok)
+				require.Equal(t, name.AsString(), dbName.AsString())
+			}
+
+			for _, reg := range regs {
+				require.NoError(t, reg.Unregister())
+			}
+
+			rm = metricdata.ResourceMetrics{}
+			require.NoError(t, reader.Collect(context.TODO(), &rm))
+			require.Empty(t, metricAttributes(rm, test.metric))
+		})
+	}
+}
+
+func TestConnectionsUsage(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	db, err := sql.Open("sqlite3", "file:usage?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer db.Close()
+
+	reg, err := RegisterDBStatsMetrics(db, WithMeterProvider(meterProvider))
+	require.NoError(t, err)
+	defer reg.Unregister()
+
+	conn, err := db.Conn(context.TODO())
+	require.NoError(t, err)
+	defer conn.Close()
+	reg.RecordWaitTime(context.TODO(), 5*time.Millisecond)
+	reg.RecordTimeout(context.TODO())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.TODO(), &rm))
+
+	states := map[string]int64{}
+	var (
+		waits    []metricdata.HistogramDataPoint[float64]
+		timeouts int64
+	)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		switch m.Name {
+		case "db.client.connections.usage":
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				state, _ := dp.Attributes.Value(connectionState)
+				states[state.AsString()] = dp.Value
+			}
+		case "db.client.connections.wait_time":
+			waits = m.Data.(metricdata.Histogram[float64]).DataPoints
+		case "db.client.connections.timeouts":
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				timeouts += dp.Value
+			}
+		}
+	}
+
+	require.Equal(t, map[string]int64{"idle": 0, "used": 1}, states)
+	require.Equal(t, 1, len(waits))
+	require.Equal(t, uint64(1), waits[0].Count)
+	require.Equal(t, 0.005, waits[0].Sum)
+	require.Equal(t, int64(1), timeouts)
+}
+
+// metricAttributes returns the attribute sets of the data points of the named int64 metric.
+func metricAttributes(rm metricdata.ResourceMetrics, name string) []attribute.Set {
+	var sets []attribute.Set
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != name {
+				continue
+			}
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				for _, dp := range data.DataPoints {
+					sets = append(sets, dp.Attributes)
+				}
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					sets = append(sets, dp.Attributes)
+				}
+			}
+		}
+	}
+	return sets
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// OperationMetrics records the duration and the count of the db client operations.
+type OperationMetrics struct {
+	duration metric.Float64Histogram
+	count    metric.Int64Counter
+	slow     metric.Int64Counter
+}
+
+// NewOperationMetrics creates the operation instruments from the meter provider.
+func NewOperationMetrics(meterProvider metric.MeterProvider) (*OperationMetrics, error) {
+	meter := meterProvider.Meter(scopeName)
+
+	duration, err := meter.Float64Histogram(
+		"db.client.operation.duration",
+		metric.WithDescription("Duration of database client operations"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := meter.Int64Counter(
+		"db.client.operations",
+		metric.WithDescription("The number of database client operations"),
+		metric.WithUnit("{operation}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	slow, err := meter.Int64Counter(
+		"db.client.operations.slow",
+		metric.WithDescription("The number of database client operations lasting over the slow query threshold"),
+		metric.WithUnit("{operation}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OperationMetrics{duration: duration, count: count, slow: slow}, nil
+}
+
+// Record records an operation, the duration is skipped when it is negative.
+func (m *OperationMetrics) Record(ctx context.Context, duration time.Duration, attrs ...attribute.KeyValue) {
+	opt := metric.WithAttributes(attrs...)
+
+	if duration >= 0 {
+		m.duration.Record(ctx, duration.Seconds(), opt)
+	}
+	m.count.Add(ctx, 1, opt)
+}
+
+// RecordSlow records a slow operation.
+func (m *OperationMetrics) RecordSlow(ctx context.Context, attrs ...attribute.KeyValue) {
+	m.slow.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// The attributes of the db.client.connections.* metrics of the semantic convention v1.17.0, as the spans.
+var (
+	poolName        = attribute.Key("pool.name")
+	connectionState = attribute.Key("state")
+)
+
+// registerDBStats registers the db.client.connections.* DBStats metrics of the semantic convention.
+// database/sql neither times the acquisitions nor counts their timeouts, the wait_time and timeouts
+// are recorded by the callers running the statements.
+func registerDBStats(meter metric.Meter, db *sql.DB, attrs []attribute.KeyValue) (*Registration, error) {
+	usage, err := meter.Int64ObservableUpDownCounter(
+		"db.client.connections.usage",
+		metric.WithDescription("The number of connections that are currently in state described by the state attribute"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	maxConns, err := meter.Int64ObservableUpDownCounter(
+		"db.client.connections.max",
+		metric.WithDescription("The maximum number of open connections allowed"),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	waitTime, err := meter.Float64Histogram(
+		"db.client.connections.wait_time",
+		metric.WithDescription("The time it took to obtain an open connection from the pool"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	timeouts, err := meter.Int64Counter(
+		"db.client.connections.timeouts",
+		metric.WithDescription("The number of connection timeouts that have occurred trying to obtain a connection from the pool"),
+		metric.WithUnit("{timeout}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		opt  = metric.WithAttributes(attrs...)
+		idle = metric.WithAttributes(append(attrs[:len(attrs):len(attrs)], connectionState.String("idle"))...)
+		used = metric.WithAttributes(append(attrs[:len(attrs):len(attrs)], connectionState.String("used"))...)
+	)
+
+	reg, err := meter.RegisterCallback(
+		func(ctx context.Context, o metric.Observer) error {
+			stats := db.Stats()
+
+			o.ObserveInt64(usage, int64(stats.Idle), idle)
+			o.ObserveInt64(usage, int64(stats.InUse), used)
+			o.ObserveInt64(maxConns, int64(stats.MaxOpenConnections), opt)
+			return nil
+		},
+		usage,
+		maxConns,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Registration{reg: reg, waitTime: waitTime, timeouts: timeouts, opt: opt}, nil
+}
+
+// RecordWaitTime records the time a statement waited for a connection of the pool, in seconds.
+func (r *Registration) RecordWaitTime(ctx context.Context, d time.Duration) {
+	if r.waitTime != nil {
+		r.waitTime.Record(ctx, d.Seconds(), r.opt)
+	}
+}
+
+// RecordTimeout counts a statement which failed to obtain a connection of the pool before its deadline.
+func (r *Registration) RecordTimeout(ctx context.Context) {
+	if r.timeouts != nil {
+		r.timeouts.Add(ctx, 1, r.opt)
+	}
+}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/contrib/propagators/ot"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// applyEnv configures cfg from the OTEL_* environment variables, the options are applied afterwards
+// so they take precedence. OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES are read by the resource,
+// the OTEL_EXPORTER_OTLP_* ones by the exporters. The invalid values are handed to otel.Handle and ignored.
+func applyEnv(cfg *config) {
+	if v, ok := lookupEnv("OTEL_SDK_DISABLED"); ok && strings.EqualFold(v, "true") {
+		cfg.enableTracing = false
+		cfg.enableMetrics = false
+	}
+
+	if v, ok := lookupEnv("OTEL_TRACES_EXPORTER"); ok {
+		switch v {
+		case "otlp":
+		case "none":
+			cfg.enableTracing = false
+		default:
+			otel.Handle(fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q", v))
+		}
+	}
+
+	if v, ok := lookupEnv("OTEL_METRICS_EXPORTER"); ok {
+		switch v {
+		case "otlp":
+		case "none":
+			cfg.enableMetrics = false
+		default:
+			otel.Handle(fmt.Errorf("unsupported OTEL_METRICS_EXPORTER %q", v))
+		}
+	}
+
+	if v, ok := lookupEnv("OTEL_TRACES_SAMPLER"); ok {
+		arg, _ := lookupEnv("OTEL_TRACES_SAMPLER_ARG")
+		if sampler, err := samplerFromEnv(v, arg); err != nil {
+			otel.Handle(err)
+		} else {
+			cfg.sampler = sampler
+		}
+	}
+
+	if v, ok := lookupEnv("OTEL_PROPAGATORS"); ok {
+		if propagator, err := propagatorFromEnv(v); err != nil {
+			otel.Handle(err)
+		} else {
+			cfg.textMapPropagator = propagator
+		}
+	}
+
+	envDuration("OTEL_BSP_SCHEDULE_DELAY", &cfg.batch.scheduleDelay)
+	envDuration("OTEL_BSP_EXPORT_TIMEOUT", &cfg.batch.exportTimeout)
+	envInt("OTEL_BSP_MAX_QUEUE_SIZE", &cfg.batch.maxQueueSize)
+	envInt("OTEL_BSP_MAX_EXPORT_BATCH_SIZE", &cfg.batch.maxExportBatchSize)
+	envDuration("OTEL_METRIC_EXPORT_INTERVAL", &cfg.metricExportInterval)
+}
+
+func lookupEnv(key string) (string, bool) {
+	v, ok := os.LookupEnv(key)
+	v = strings.TrimSpace(v)
+	return v, ok && v != ""
+}
+
+// envDuration sets d from the milliseconds of the key.
+func envDuration(key string, d *time.Duration) {
+	var ms int
+	if envInt(key, &ms) {
+		*d = time.Duration(ms) * time.Millisecond
+	}
+}
+
+func envInt(key string, n *int) bool {
+	v, ok := lookupEnv(key)
+	if !ok {
+		return false
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil || i <= 0 {
+		otel.Handle(fmt.Errorf("invalid %s %q", key, v))
+		return false
+	}
+	*n = i
+	return true
+}
+
+// samplerFromEnv returns the sampler of the OTEL_TRACES_SAMPLER name and OTEL_TRACES_SAMPLER_ARG argument.
+func samplerFromEnv(name, arg string) (sdktrace.Sampler, error) {
+	ratio := 1.0
+	if arg != "" {
+		r, err := strconv.ParseFloat(arg, 64)
+		if err != nil || r < 0 || r > 1 {
+			return nil, fmt.Errorf("invalid OTEL_TRACES_SAMPLER_ARG %q", arg)
+		}
+		ratio = r
+	}
+
+	switch name {
+	case "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	case "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "parentbased_traceidratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_TRACES_SAMPLER %q", name)
+	}
+}
+
+// propagatorFromEnv returns the composite propagator of the comma separated OTEL_PROPAGATORS names.
+func propagatorFromEnv(names string) (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "b3":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "jaeger":
+			propagators = append(propagators, jaeger.Jaeger{})
+		case "ottrace":
+			propagators = append(propagators, ot.OT{})
+		case "none":
+			return propagation.NewCompositeTextMapPropagator(), nil
+		default:
+			return nil, fmt.Errorf("unsupported OTEL_PROPAGATORS %q", name)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
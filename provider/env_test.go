@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+func TestEnvPrecedence(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		opts   []Option
+		assert func(t *testing.T, cfg *config)
+	}{
+		{
+			name: "sdk disabled",
+			env:  map[string]string{"OTEL_SDK_DISABLED": "true"},
+			assert: func(t *testing.T, cfg *config) {
+				require.False(t, cfg.enableTracing)
+				require.False(t, cfg.enableMetrics)
+			},
+		},
+		{
+			name: "sdk disabled, tracing option",
+			env:  map[string]string{"OTEL_SDK_DISABLED": "true"},
+			opts: []Option{WithEnableTracing(true)},
+			assert: func(t *testing.T, cfg *config) {
+				require.True(t, cfg.enableTracing)
+				require.False(t, cfg.enableMetrics)
+			},
+		},
+		{
+			name: "exporters none",
+			env:  map[string]string{"OTEL_TRACES_EXPORTER": "none", "OTEL_METRICS_EXPORTER": "otlp"},
+			assert: func(t *testing.T, cfg *config) {
+				require.False(t, cfg.enableTracing)
+				require.True(t, cfg.enableMetrics)
+			},
+		},
+		{
+			name: "metrics exporter none, metrics option",
+			env:  map[string]string{"OTEL_METRICS_EXPORTER": "none"},
+			opts: []Option{WithEnableMetrics(true)},
+			assert: func(t *testing.T, cfg *config) {
+				require.True(t, cfg.enableMetrics)
+			},
+		},
+		{
+			name: "unsupported exporter",
+			env:  map[string]string{"OTEL_TRACES_EXPORTER": "zipkin"},
+			assert: func(t *testing.T, cfg *config) {
+				require.True(t, cfg.enableTracing)
+			},
+		},
+		{
+			name: "sampler",
+			env:  map[string]string{"OTEL_TRACES_SAMPLER": "parentbased_traceidratio", "OTEL_TRACES_SAMPLER_ARG": "0.25"},
+			assert: func(t *testing.T, cfg *config) {
+				require.Equal(t, sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.25)).Description(), cfg.sampler.Description())
+			},
+		},
+		{
+			name: "invalid sampler argument",
+			env:  map[string]string{"OTEL_TRACES_SAMPLER": "traceidratio", "OTEL_TRACES_SAMPLER_ARG": "2"},
+			assert: func(t *testing.T, cfg *config) {
+				require.Nil(t, cfg.sampler)
+			},
+		},
+		{
+			name: "no propagator",
+			assert: func(t *testing.T, cfg *config) {
+				require.Nil(t, cfg.textMapPropagator)
+			},
+		},
+		{
+			name: "propagators",
+			env:  map[string]string{"OTEL_PROPAGATORS": "tracecontext,baggage"},
+			assert: func(t *testing.T, cfg *config) {
+				require.ElementsMatch(t, []string{"traceparent", "tracestate", "baggage"}, cfg.textMapPropagator.Fields())
+			},
+		},
+		{
+			name: "propagators none",
+			env:  map[string]string{"OTEL_PROPAGATORS": "none"},
+			assert: func(t *testing.T, cfg *config) {
+				require.Empty(t, cfg.textMapPropagator.Fields())
+			},
+		},
+		{
+			name: "propagators, propagator option",
+			env:  map[string]string{"OTEL_PROPAGATORS": "b3"},
+			opts: []Option{WithTextMapPropagator(propagation.Baggage{})},
+			assert: func(t *testing.T, cfg *config) {
+				require.Equal(t, propagation.Baggage{}, cfg.textMapPropagator)
+			},
+		},
+		{
+			name: "batch span processor",
+			env: map[string]string{
+				"OTEL_BSP_SCHEDULE_DELAY":        "500",
+				"OTEL_BSP_EXPORT_TIMEOUT":        "10000",
+				"OTEL_BSP_MAX_QUEUE_SIZE":        "4096",
+				"OTEL_BSP_MAX_EXPORT_BATCH_SIZE": "1024",
+			},
+			assert: func(t *testing.T, cfg *config) {
+				require.Equal(t, batchConfig{
+					maxQueueSize:       4096,
+					maxExportBatchSize: 1024,
+					scheduleDelay:      500 * time.Millisecond,
+					exportTimeout:      10 * time.Second,
+				}, cfg.batch)
+			},
+		},
+		{
+			name: "batch span processor, batch options",
+			env: map[string]string{
+				"OTEL_BSP_SCHEDULE_DELAY": "500",
+				"OTEL_BSP_MAX_QUEUE_SIZE": "4096",
+			},
+			opts: []Option{
+				WithBatchScheduleDelay(time.Second),
+				WithBatchExportTimeout(time.Minute),
+				WithBatchMaxQueueSize(512),
+				WithBatchMaxExportBatchSize(128),
+			},
+			assert: func(t *testing.T, cfg *config) {
+				require.Equal(t, batchConfig{
+					maxQueueSize:       512,
+					maxExportBatchSize: 128,
+					scheduleDelay:      time.Second,
+					exportTimeout:      time.Minute,
+				}, cfg.batch)
+			},
+		},
+		{
+			name: "invalid batch size",
+			env:  map[string]string{"OTEL_BSP_MAX_QUEUE_SIZE": "many"},
+			assert: func(t *testing.T, cfg *config) {
+				require.Zero(t, cfg.batch.maxQueueSize)
+			},
+		},
+		{
+			name: "metric export interval",
+			env:  map[string]string{"OTEL_METRIC_EXPORT_INTERVAL": "15000"},
+			assert: func(t *testing.T, cfg *config) {
+				require.Equal(t, 15*time.Second, cfg.metricExportInterval)
+			},
+		},
+		{
+			name: "metric export interval, interval option",
+			env:  map[string]string{"OTEL_METRIC_EXPORT_INTERVAL": "15000"},
+			opts: []Option{WithMetricExportInterval(time.Second)},
+			assert: func(t *testing.T, cfg *config) {
+				require.Equal(t, time.Second, cfg.metricExportInterval)
+			},
+		},
+		{
+			name: "service name",
+			env:  map[string]string{"OTEL_SERVICE_NAME": "env-service"},
+			assert: func(t *testing.T, cfg *config) {
+				require.Equal(t, "env-service", serviceName(t, cfg))
+			},
+		},
+		{
+			name: "service name, service name option",
+			env:  map[string]string{"OTEL_SERVICE_NAME": "env-service"},
+			opts: []Option{WithServiceName("option-service")},
+			assert: func(t *testing.T, cfg *config) {
+				require.Equal(t, "option-service", serviceName(t, cfg))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+
+			test.assert(t, newConfig(test.opts))
+		})
+	}
+}
+
+func serviceName(t *testing.T, cfg *config) string {
+	res := newResource(context.TODO(), cfg)
+	require.NotEmpty(t, res.SchemaURL())
+
+	v, ok := res.Set().Value(semconv.ServiceNameKey)
+	require.True(t, ok)
+	return v.AsString()
+}
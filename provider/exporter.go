@@ -0,0 +1,274 @@
+package provider
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/credentials"
+)
+
+// The OTLP export protocols.
+const (
+	ProtocolGRPC         = "grpc"
+	ProtocolHTTPProtobuf = "http/protobuf"
+)
+
+// exportProtocol returns the protocol of the signal: the option, the signal environment variable,
+// e.g. OTEL_EXPORTER_OTLP_TRACES_PROTOCOL, OTEL_EXPORTER_OTLP_PROTOCOL, then grpc.
+func exportProtocol(cfg *config, signalEnv string) (string, error) {
+	protocol := cfg.exportProtocol
+	for _, env := range []string{signalEnv, "OTEL_EXPORTER_OTLP_PROTOCOL"} {
+		if protocol != "" {
+			break
+		}
+		protocol = os.Getenv(env)
+	}
+
+	switch protocol {
+	case "":
+		return ProtocolGRPC, nil
+	case ProtocolGRPC, ProtocolHTTPProtobuf:
+		return protocol, nil
+	default:
+		return "", fmt.Errorf("unsupported otlp export protocol %q", protocol)
+	}
+}
+
+// exportConfig is the export settings of a signal.
+type exportConfig struct {
+	endpoint    string
+	headers     map[string]string
+	certFile    string // the TLS client certificate
+	keyFile     string
+	caFile      string
+	compression string
+	timeout     time.Duration
+	insecure    bool // no client transport security
+}
+
+// or returns c with its unset settings taken from shared.
+func (c exportConfig) or(shared exportConfig) exportConfig {
+	if c.endpoint == "" {
+		c.endpoint = shared.endpoint
+	}
+	if len(c.headers) == 0 {
+		c.headers = shared.headers
+	}
+	// the transport security of the signal, insecure or TLS, overrides the shared one as a whole.
+	switch {
+	case c.insecure:
+		c.certFile, c.keyFile, c.caFile = "", "", ""
+	case c.certFile != "" || c.caFile != "":
+		if c.certFile == "" {
+			c.certFile, c.keyFile = shared.certFile, shared.keyFile
+		}
+		if c.caFile == "" {
+			c.caFile = shared.caFile
+		}
+	default:
+		c.certFile, c.keyFile, c.caFile = shared.certFile, shared.keyFile, shared.caFile
+		c.insecure = shared.insecure
+	}
+	if c.compression == "" {
+		c.compression = shared.compression
+	}
+	if c.timeout == 0 {
+		c.timeout = shared.timeout
+	}
+	return c
+}
+
+// gzip reports whether the export requests are compressed.
+func (c exportConfig) gzip() (bool, error) {
+	switch c.compression {
+	case "", "none":
+		return false, nil
+	case "gzip":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported otlp export compression %q", c.compression)
+	}
+}
+
+// tlsConfig returns the TLS client config of the certificate files, nil when there are none.
+func (c exportConfig) tlsConfig() (*tls.Config, error) {
+	if c.certFile == "" && c.caFile == "" {
+		return nil, nil
+	}
+
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.caFile != "" {
+		pem, err := os.ReadFile(c.caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", c.caFile)
+		}
+	}
+	if c.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+func newTraceExporter(ctx context.Context, cfg *config) (sdktrace.SpanExporter, error) {
+	protocol, err := exportProtocol(cfg, "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if err != nil {
+		return nil, err
+	}
+
+	exportCfg := cfg.traceExport.or(cfg.export)
+	gzip, err := exportCfg.gzip()
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg, err := exportCfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// trace client
+	var traceClient otlptrace.Client
+	switch protocol {
+	case ProtocolHTTPProtobuf:
+		var traceClientOpts []otlptracehttp.Option
+		if exportCfg.endpoint != "" {
+			traceClientOpts = append(traceClientOpts, otlptracehttp.WithEndpoint(exportCfg.endpoint))
+		}
+		if len(exportCfg.headers) > 0 {
+			traceClientOpts = append(traceClientOpts, otlptracehttp.WithHeaders(exportCfg.headers))
+		}
+		if exportCfg.insecure {
+			traceClientOpts = append(traceClientOpts, otlptracehttp.WithInsecure())
+		} else if tlsCfg != nil {
+			traceClientOpts = append(traceClientOpts, otlptracehttp.WithTLSClientConfig(tlsCfg))
+		}
+		if gzip {
+			traceClientOpts = append(traceClientOpts, otlptracehttp.WithCompression(otlptracehttp.GzipCompression))
+		}
+		if exportCfg.timeout > 0 {
+			traceClientOpts = append(traceClientOpts, otlptracehttp.WithTimeout(exportCfg.timeout))
+		}
+
+		traceClient = otlptracehttp.NewClient(traceClientOpts...)
+	default:
+		var traceClientOpts []otlptracegrpc.Option
+		if exportCfg.endpoint != "" {
+			traceClientOpts = append(traceClientOpts, otlptracegrpc.WithEndpoint(exportCfg.endpoint))
+		}
+		if len(exportCfg.headers) > 0 {
+			traceClientOpts = append(traceClientOpts, otlptracegrpc.WithHeaders(exportCfg.headers))
+		}
+		if exportCfg.insecure {
+			traceClientOpts = append(traceClientOpts, otlptracegrpc.WithInsecure())
+		} else if tlsCfg != nil {
+			traceClientOpts = append(traceClientOpts, otlptracegrpc.WithTLSCredentials(credentials.NewTLS(tlsCfg)))
+		}
+		if gzip {
+			traceClientOpts = append(traceClientOpts, otlptracegrpc.WithCompressor("gzip"))
+		}
+		if exportCfg.timeout > 0 {
+			traceClientOpts = append(traceClientOpts, otlptracegrpc.WithTimeout(exportCfg.timeout))
+		}
+
+		traceClient = otlptracegrpc.NewClient(traceClientOpts...)
+	}
+
+	// trace exporter, a nil *otlptrace.Exporter must not become a non-nil SpanExporter.
+	exp, err := otlptrace.New(ctx, traceClient)
+	if err != nil {
+		return nil, err
+	}
+	return exp, nil
+}
+
+func newMetricExporter(ctx context.Context, cfg *config) (sdkmetric.Exporter, error) {
+	protocol, err := exportProtocol(cfg, "OTEL_EXPORTER_OTLP_METRICS_PROTOCOL")
+	if err != nil {
+		return nil, err
+	}
+
+	exportCfg := cfg.metricExport.or(cfg.export)
+	gzip, err := exportCfg.gzip()
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg, err := exportCfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	switch protocol {
+	case ProtocolHTTPProtobuf:
+		metricsClientOpts := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithAggregationSelector(sdkmetric.DefaultAggregationSelector),
+		}
+		if exportCfg.endpoint != "" {
+			metricsClientOpts = append(metricsClientOpts, otlpmetrichttp.WithEndpoint(exportCfg.endpoint))
+		}
+		if len(exportCfg.headers) > 0 {
+			metricsClientOpts = append(metricsClientOpts, otlpmetrichttp.WithHeaders(exportCfg.headers))
+		}
+		if exportCfg.insecure {
+			metricsClientOpts = append(metricsClientOpts, otlpmetrichttp.WithInsecure())
+		} else if tlsCfg != nil {
+			metricsClientOpts = append(metricsClientOpts, otlpmetrichttp.WithTLSClientConfig(tlsCfg))
+		}
+		if gzip {
+			metricsClientOpts = append(metricsClientOpts, otlpmetrichttp.WithCompression(otlpmetrichttp.GzipCompression))
+		}
+		if exportCfg.timeout > 0 {
+			metricsClientOpts = append(metricsClientOpts, otlpmetrichttp.WithTimeout(exportCfg.timeout))
+		}
+
+		exp, err := otlpmetrichttp.New(ctx, metricsClientOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return exp, nil
+	default:
+		metricsClientOpts := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithAggregationSelector(sdkmetric.DefaultAggregationSelector),
+		}
+		if exportCfg.endpoint != "" {
+			metricsClientOpts = append(metricsClientOpts, otlpmetricgrpc.WithEndpoint(exportCfg.endpoint))
+		}
+		if len(exportCfg.headers) > 0 {
+			metricsClientOpts = append(metricsClientOpts, otlpmetricgrpc.WithHeaders(exportCfg.headers))
+		}
+		if exportCfg.insecure {
+			metricsClientOpts = append(metricsClientOpts, otlpmetricgrpc.WithInsecure())
+		} else if tlsCfg != nil {
+			metricsClientOpts = append(metricsClientOpts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(tlsCfg)))
+		}
+		if gzip {
+			metricsClientOpts = append(metricsClientOpts, otlpmetricgrpc.WithCompressor("gzip"))
+		}
+		if exportCfg.timeout > 0 {
+			metricsClientOpts = append(metricsClientOpts, otlpmetricgrpc.WithTimeout(exportCfg.timeout))
+		}
+
+		exp, err := otlpmetricgrpc.New(ctx, metricsClientOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return exp, nil
+	}
+}
@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportProtocol(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		env       string
+		signalEnv string
+		want      string
+		wantErr   bool
+	}{
+		{name: "default", want: ProtocolGRPC},
+		{name: "env", env: ProtocolHTTPProtobuf, want: ProtocolHTTPProtobuf},
+		{name: "signal env", env: ProtocolGRPC, signalEnv: ProtocolHTTPProtobuf, want: ProtocolHTTPProtobuf},
+		{name: "option", opts: []Option{WithExportProtocol(ProtocolGRPC)}, env: ProtocolHTTPProtobuf, want: ProtocolGRPC},
+		{name: "unsupported", opts: []Option{WithExportProtocol("http/json")}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", test.env)
+			t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", test.signalEnv)
+
+			got, err := exportProtocol(newConfig(test.opts), "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+			if test.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestExportConfig(t *testing.T) {
+	cfg := newConfig([]Option{
+		WithExportEndpoint("collector:4317"),
+		WithHeaders(map[string]string{"tenant": "shop"}),
+		WithCompression("gzip"),
+		WithExportTimeout(time.Second),
+		WithTraceExportEndpoint("tracing:4317"),
+		WithTraceExportTimeout(time.Minute),
+		WithTraceInsecure(),
+		WithMetricHeaders(map[string]string{"tenant": "metrics"}),
+	})
+
+	require.Equal(t, exportConfig{
+		endpoint:    "tracing:4317",
+		headers:     map[string]string{"tenant": "shop"},
+		compression: "gzip",
+		timeout:     time.Minute,
+		insecure:    true,
+	}, cfg.traceExport.or(cfg.export))
+
+	require.Equal(t, exportConfig{
+		endpoint:    "collector:4317",
+		headers:     map[string]string{"tenant": "metrics"},
+		compression: "gzip",
+		timeout:     time.Second,
+	}, cfg.metricExport.or(cfg.export))
+}
+
+func TestExportInsecure(t *testing.T) {
+	cfg := newConfig([]Option{WithInsecure()})
+	require.True(t, cfg.traceExport.or(cfg.export).insecure)
+	require.True(t, cfg.metricExport.or(cfg.export).insecure)
+
+	cfg = newConfig([]Option{WithMetricInsecure()})
+	require.False(t, cfg.traceExport.or(cfg.export).insecure)
+	require.True(t, cfg.metricExport.or(cfg.export).insecure)
+
+	// the per-signal TLS settings override the shared insecure.
+	cfg = newConfig([]Option{WithInsecure(), WithTraceCACertificate("ca.pem"), WithMetricTLSClientCertificate("cert.pem", "key.pem")})
+	trace, metric := cfg.traceExport.or(cfg.export), cfg.metricExport.or(cfg.export)
+	require.False(t, trace.insecure)
+	require.Equal(t, "ca.pem", trace.caFile)
+	require.False(t, metric.insecure)
+	require.Equal(t, "cert.pem", metric.certFile)
+
+	cfg = newConfig([]Option{WithInsecure(), WithTraceCACertificate("ca.pem")})
+	require.False(t, cfg.traceExport.or(cfg.export).insecure)
+	require.True(t, cfg.metricExport.or(cfg.export).insecure)
+
+	// the per-signal insecure overrides the shared TLS settings.
+	cfg = newConfig([]Option{WithCACertificate("ca.pem"), WithTLSClientCertificate("cert.pem", "key.pem"), WithTraceInsecure()})
+	trace, metric = cfg.traceExport.or(cfg.export), cfg.metricExport.or(cfg.export)
+	require.True(t, trace.insecure)
+	require.Empty(t, trace.caFile)
+	require.Empty(t, trace.certFile)
+	require.False(t, metric.insecure)
+	require.Equal(t, "ca.pem", metric.caFile)
+}
+
+func TestExportConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "compression", opts: []Option{WithMetricCompression("zstd")}},
+		{name: "ca file", opts: []Option{WithTraceCACertificate("testdata/missing.pem")}},
+		{name: "client certificate", opts: []Option{WithTLSClientCertificate("testdata/missing.pem", "testdata/missing.key")}},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewOpenTelemetryProviderE(context.Background(), test.opts...)
+			require.Error(t, err)
+
+			p, err := NewOpenTelemetryProviderE(context.Background(), append(test.opts, WithNoopFallback())...)
+			require.NoError(t, err)
+			require.NoError(t, p.Shutdown(context.Background()))
+		})
+	}
+}
@@ -1,10 +1,8 @@
 package provider
 
 import (
-	"go.opentelemetry.io/contrib/propagators/b3"
-	"go.opentelemetry.io/contrib/propagators/jaeger"
-	"go.opentelemetry.io/contrib/propagators/opencensus"
-	"go.opentelemetry.io/contrib/propagators/ot"
+	"time"
+
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
 	sdkresource "go.opentelemetry.io/otel/sdk/resource"
@@ -27,9 +25,10 @@ type config struct {
 	enableTracing bool
 	enableMetrics bool
 
-	exportInsecure bool
-	exportEndpoint string
-	exportHeaders  map[string]string
+	exportProtocol string
+	export         exportConfig // shared by the signals
+	traceExport    exportConfig
+	metricExport   exportConfig
 
 	resource          *sdkresource.Resource
 	sdkTracerProvider *sdktrace.TracerProvider
@@ -38,27 +37,51 @@ type config struct {
 	resourceDetectors  []sdkresource.Detector
 
 	textMapPropagator propagation.TextMapPropagator
+
+	sampler              sdktrace.Sampler
+	batch                batchConfig
+	spanProcessors       []sdktrace.SpanProcessor
+	metricExportInterval time.Duration
+
+	noopFallback bool
+}
+
+// batchConfig configures the batch span processor, the zero values keep the sdk defaults.
+type batchConfig struct {
+	maxQueueSize       int
+	maxExportBatchSize int
+	scheduleDelay      time.Duration
+	exportTimeout      time.Duration
+}
+
+func (b batchConfig) options() []sdktrace.BatchSpanProcessorOption {
+	var opts []sdktrace.BatchSpanProcessorOption
+	if b.maxQueueSize > 0 {
+		opts = append(opts, sdktrace.WithMaxQueueSize(b.maxQueueSize))
+	}
+	if b.maxExportBatchSize > 0 {
+		opts = append(opts, sdktrace.WithMaxExportBatchSize(b.maxExportBatchSize))
+	}
+	if b.scheduleDelay > 0 {
+		opts = append(opts, sdktrace.WithBatchTimeout(b.scheduleDelay))
+	}
+	if b.exportTimeout > 0 {
+		opts = append(opts, sdktrace.WithExportTimeout(b.exportTimeout))
+	}
+	return opts
 }
 
 func defaultConfig() *config {
 	return &config{
 		enableTracing: true,
 		enableMetrics: true,
-		textMapPropagator: propagation.NewCompositeTextMapPropagator(
-			propagation.NewCompositeTextMapPropagator(
-				b3.New(),
-				ot.OT{},
-				jaeger.Jaeger{},
-				opencensus.Binary{},
-				propagation.Baggage{},
-				propagation.TraceContext{},
-			),
-		),
 	}
 }
 
+// newConfig applies the OTEL_* environment variables and then the options, which take precedence.
 func newConfig(opts []Option) *config {
 	cfg := defaultConfig()
+	applyEnv(cfg)
 
 	for _, opt := range opts {
 		opt.apply(cfg)
@@ -105,7 +128,7 @@ func WithResource(resource *sdkresource.Resource) Option {
 // WithExportEndpoint configures export endpoint.
 func WithExportEndpoint(endpoint string) Option {
 	return option(func(cfg *config) {
-		cfg.exportEndpoint = endpoint
+		cfg.export.endpoint = endpoint
 	})
 }
 
@@ -123,7 +146,8 @@ func WithEnableMetrics(enableMetrics bool) Option {
 	})
 }
 
-// WithTextMapPropagator configures propagation.
+// WithTextMapPropagator configures the global propagator, OTEL_PROPAGATORS when unset.
+// The global propagator set by the application is kept when neither is set.
 func WithTextMapPropagator(p propagation.TextMapPropagator) Option {
 	return option(func(cfg *config) {
 		cfg.textMapPropagator = p
@@ -137,16 +161,216 @@ func WithResourceDetector(detector sdkresource.Detector) Option {
 	})
 }
 
-// WithHeaders configures gRPC requests headers for exported telemetry data.
+// WithExportProtocol configures the OTLP protocol of the trace and metric exporters, ProtocolGRPC or ProtocolHTTPProtobuf.
+// The OTEL_EXPORTER_OTLP_PROTOCOL environment variable is used when unset, grpc by default.
+func WithExportProtocol(protocol string) Option {
+	return option(func(cfg *config) {
+		cfg.exportProtocol = protocol
+	})
+}
+
+// WithHeaders configures gRPC or HTTP requests headers for exported telemetry data.
 func WithHeaders(headers map[string]string) Option {
 	return option(func(cfg *config) {
-		cfg.exportHeaders = headers
+		cfg.export.headers = headers
 	})
 }
 
-// WithInsecure disables client transport security for the exporter's gRPC.
+// WithInsecure disables client transport security for the exporter's gRPC or HTTP.
 func WithInsecure() Option {
 	return option(func(cfg *config) {
-		cfg.exportInsecure = true
+		cfg.export.insecure = true
+	})
+}
+
+// WithNoopFallback degrades to the no-op providers when NewOpenTelemetryProviderE fails to initialize,
+// the error is handed to otel.Handle instead of being returned.
+func WithNoopFallback() Option {
+	return option(func(cfg *config) {
+		cfg.noopFallback = true
+	})
+}
+
+// WithTLSClientCertificate configures the PEM files of the exporters TLS client certificate and key.
+func WithTLSClientCertificate(certFile, keyFile string) Option {
+	return option(func(cfg *config) {
+		cfg.export.certFile, cfg.export.keyFile = certFile, keyFile
+	})
+}
+
+// WithCACertificate configures the PEM file of the CA bundle verifying the collector certificate.
+func WithCACertificate(caFile string) Option {
+	return option(func(cfg *config) {
+		cfg.export.caFile = caFile
+	})
+}
+
+// WithCompression configures the exporters compression, gzip or none.
+func WithCompression(compression string) Option {
+	return option(func(cfg *config) {
+		cfg.export.compression = compression
+	})
+}
+
+// WithExportTimeout configures the exporters timeout of a batch export.
+func WithExportTimeout(timeout time.Duration) Option {
+	return option(func(cfg *config) {
+		cfg.export.timeout = timeout
+	})
+}
+
+// WithTraceExportEndpoint configures the trace export endpoint, WithExportEndpoint when unset.
+func WithTraceExportEndpoint(endpoint string) Option {
+	return option(func(cfg *config) {
+		cfg.traceExport.endpoint = endpoint
+	})
+}
+
+// WithTraceHeaders configures the trace export requests headers, WithHeaders when unset.
+func WithTraceHeaders(headers map[string]string) Option {
+	return option(func(cfg *config) {
+		cfg.traceExport.headers = headers
+	})
+}
+
+// WithTraceTLSClientCertificate configures the trace exporter TLS client certificate, WithTLSClientCertificate when unset.
+// It overrides WithInsecure for the trace exporter.
+func WithTraceTLSClientCertificate(certFile, keyFile string) Option {
+	return option(func(cfg *config) {
+		cfg.traceExport.certFile, cfg.traceExport.keyFile = certFile, keyFile
+	})
+}
+
+// WithTraceCACertificate configures the trace exporter CA bundle, WithCACertificate when unset.
+// It overrides WithInsecure for the trace exporter.
+func WithTraceCACertificate(caFile string) Option {
+	return option(func(cfg *config) {
+		cfg.traceExport.caFile = caFile
+	})
+}
+
+// WithTraceCompression configures the trace exporter compression, WithCompression when unset.
+func WithTraceCompression(compression string) Option {
+	return option(func(cfg *config) {
+		cfg.traceExport.compression = compression
+	})
+}
+
+// WithTraceExportTimeout configures the trace exporter timeout, WithExportTimeout when unset.
+func WithTraceExportTimeout(timeout time.Duration) Option {
+	return option(func(cfg *config) {
+		cfg.traceExport.timeout = timeout
+	})
+}
+
+// WithTraceInsecure disables client transport security for the trace exporter, overriding the shared TLS settings.
+// WithInsecure applies when neither WithTraceInsecure nor a trace TLS setting is set.
+func WithTraceInsecure() Option {
+	return option(func(cfg *config) {
+		cfg.traceExport.insecure = true
+	})
+}
+
+// WithMetricExportEndpoint configures the metric export endpoint, WithExportEndpoint when unset.
+func WithMetricExportEndpoint(endpoint string) Option {
+	return option(func(cfg *config) {
+		cfg.metricExport.endpoint = endpoint
+	})
+}
+
+// WithMetricHeaders configures the metric export requests headers, WithHeaders when unset.
+func WithMetricHeaders(headers map[string]string) Option {
+	return option(func(cfg *config) {
+		cfg.metricExport.headers = headers
+	})
+}
+
+// WithMetricTLSClientCertificate configures the metric exporter TLS client certificate, WithTLSClientCertificate when unset.
+// It overrides WithInsecure for the metric exporter.
+func WithMetricTLSClientCertificate(certFile, keyFile string) Option {
+	return option(func(cfg *config) {
+		cfg.metricExport.certFile, cfg.metricExport.keyFile = certFile, keyFile
+	})
+}
+
+// WithMetricCACertificate configures the metric exporter CA bundle, WithCACertificate when unset.
+// It overrides WithInsecure for the metric exporter.
+func WithMetricCACertificate(caFile string) Option {
+	return option(func(cfg *config) {
+		cfg.metricExport.caFile = caFile
+	})
+}
+
+// WithMetricCompression configures the metric exporter compression, WithCompression when unset.
+func WithMetricCompression(compression string) Option {
+	return option(func(cfg *config) {
+		cfg.metricExport.compression = compression
+	})
+}
+
+// WithMetricExportTimeout configures the metric exporter timeout, WithExportTimeout when unset.
+func WithMetricExportTimeout(timeout time.Duration) Option {
+	return option(func(cfg *config) {
+		cfg.metricExport.timeout = timeout
+	})
+}
+
+// WithMetricInsecure disables client transport security for the metric exporter, overriding the shared TLS settings.
+// WithInsecure applies when neither WithMetricInsecure nor a metric TLS setting is set.
+func WithMetricInsecure() Option {
+	return option(func(cfg *config) {
+		cfg.metricExport.insecure = true
+	})
+}
+
+// WithMetricExportInterval configures the interval between the metric exports, OTEL_METRIC_EXPORT_INTERVAL when unset.
+func WithMetricExportInterval(interval time.Duration) Option {
+	return option(func(cfg *config) {
+		cfg.metricExportInterval = interval
+	})
+}
+
+// WithSampler configures the sampler of the provider-built tracer provider, OTEL_TRACES_SAMPLER when unset,
+// AlwaysSample by default. See ParentBasedRatioSampler, RateLimitedSampler and DBSampler.
+func WithSampler(sampler sdktrace.Sampler) Option {
+	return option(func(cfg *config) {
+		cfg.sampler = sampler
+	})
+}
+
+// WithBatchMaxQueueSize configures the maximum number of spans the batch span processor queues, OTEL_BSP_MAX_QUEUE_SIZE when unset.
+func WithBatchMaxQueueSize(size int) Option {
+	return option(func(cfg *config) {
+		cfg.batch.maxQueueSize = size
+	})
+}
+
+// WithBatchMaxExportBatchSize configures the maximum number of spans of an export, OTEL_BSP_MAX_EXPORT_BATCH_SIZE when unset.
+func WithBatchMaxExportBatchSize(size int) Option {
+	return option(func(cfg *config) {
+		cfg.batch.maxExportBatchSize = size
+	})
+}
+
+// WithBatchExportTimeout configures how long the batch span processor waits for an export, OTEL_BSP_EXPORT_TIMEOUT when unset.
+func WithBatchExportTimeout(timeout time.Duration) Option {
+	return option(func(cfg *config) {
+		cfg.batch.exportTimeout = timeout
+	})
+}
+
+// WithBatchScheduleDelay configures the delay between two exports of the batch span processor, OTEL_BSP_SCHEDULE_DELAY when unset.
+func WithBatchScheduleDelay(delay time.Duration) Option {
+	return option(func(cfg *config) {
+		cfg.batch.scheduleDelay = delay
+	})
+}
+
+// WithSpanProcessor appends span processors to the provider-built tracer provider, after the batch span processor,
+// e.g. a local debug processor. They see the ended spans as exported, they cannot filter what the batch span processor
+// exports. Shutdown shuts them down with the provider.
+func WithSpanProcessor(processors ...sdktrace.SpanProcessor) Option {
+	return option(func(cfg *config) {
+		cfg.spanProcessors = append(cfg.spanProcessors, processors...)
 	})
 }
@@ -2,17 +2,15 @@ package provider
 
 import (
 	"context"
+	"fmt"
 	"log"
 
 	runtimemetrics "go.opentelemetry.io/contrib/instrumentation/runtime"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	sdkresource "go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 )
 
 type OTELProvider interface {
@@ -20,20 +18,32 @@ type OTELProvider interface {
 }
 
 type defaultProvider struct {
-	traceExp      sdktrace.SpanExporter
-	metricsPusher sdkmetric.Exporter
+	traceExp       sdktrace.SpanExporter
+	metricsPusher  sdkmetric.Exporter
+	tracerProvider *sdktrace.TracerProvider // nil when the config brings its own
+	meterProvider  *sdkmetric.MeterProvider
 }
 
+// Shutdown flushes the queued spans and metrics, and shuts the span processors and the exporters down.
 func (p *defaultProvider) Shutdown(ctx context.Context) error {
 	var err error
 
-	if p.traceExp != nil {
+	// the providers shut their processors, readers and exporters down.
+	if p.tracerProvider != nil {
+		if err = p.tracerProvider.Shutdown(ctx); err != nil {
+			otel.Handle(err)
+		}
+	} else if p.traceExp != nil {
 		if err = p.traceExp.Shutdown(ctx); err != nil {
 			otel.Handle(err)
 		}
 	}
 
-	if p.metricsPusher != nil {
+	if p.meterProvider != nil {
+		if err = p.meterProvider.Shutdown(ctx); err != nil {
+			otel.Handle(err)
+		}
+	} else if p.metricsPusher != nil {
 		if err = p.metricsPusher.Shutdown(ctx); err != nil {
 			otel.Handle(err)
 		}
@@ -42,112 +52,143 @@ func (p *defaultProvider) Shutdown(ctx context.Context) error {
 	return err
 }
 
-// NewOpenTelemetryProvider Initializes an otlp trace and metrics provider.
+// NewOpenTelemetryProvider Initializes an otlp trace and metrics provider, it exits the process on init errors.
+// Use NewOpenTelemetryProviderE to handle them. It returns a no-op provider when both signals are disabled,
+// e.g. by OTEL_SDK_DISABLED=true. The standard OTEL_* environment variables configure the provider,
+// the options take precedence over them.
 func NewOpenTelemetryProvider(opts ...Option) OTELProvider {
-	var (
-		err       error
-		traceExp  sdktrace.SpanExporter
-		metricExp sdkmetric.Exporter
-	)
-
-	ctx := context.TODO()
+	p, err := NewOpenTelemetryProviderE(context.TODO(), opts...)
+	if err != nil {
+		log.Fatalf("failed to create the opentelemetry provider: %s", err)
+	}
+	return p
+}
 
+// NewOpenTelemetryProviderE initializes an otlp trace and metrics provider, and returns the init errors.
+// The global providers are only set once every signal is initialized. With WithNoopFallback,
+// an init error is handed to otel.Handle and a no-op provider is returned instead.
+func NewOpenTelemetryProviderE(ctx context.Context, opts ...Option) (OTELProvider, error) {
 	cfg := newConfig(opts)
 
+	p, err := newProvider(ctx, cfg)
+	if err != nil {
+		if cfg.noopFallback {
+			otel.Handle(err)
+			return &defaultProvider{}, nil
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func newProvider(ctx context.Context, cfg *config) (*defaultProvider, error) {
+	var (
+		err error
+		p   = &defaultProvider{}
+	)
 	if !cfg.enableTracing && !cfg.enableMetrics {
-		return nil
+		return p, nil
+	}
+
+	// fail shuts the providers and exporters created so far down.
+	fail := func(err error) (*defaultProvider, error) {
+		_ = p.Shutdown(ctx)
+		return nil, err
 	}
 
 	// resource
-	res := newResource(cfg)
+	res := newResource(ctx, cfg)
 
-	// propagator
-	if otel.GetTextMapPropagator() == nil && cfg.textMapPropagator != nil {
-		otel.SetTextMapPropagator(cfg.textMapPropagator)
-	}
+	var (
+		tracerProvider *sdktrace.TracerProvider
+		meterProvider  *sdkmetric.MeterProvider
+	)
 
 	// Tracing
 	if cfg.enableTracing {
-		// trace client
-		var traceClientOpts []otlptracegrpc.Option
-		if cfg.exportEndpoint != "" {
-			traceClientOpts = append(traceClientOpts, otlptracegrpc.WithEndpoint(cfg.exportEndpoint))
-		}
-		if len(cfg.exportHeaders) > 0 {
-			traceClientOpts = append(traceClientOpts, otlptracegrpc.WithHeaders(cfg.exportHeaders))
-		}
-		if cfg.exportInsecure {
-			traceClientOpts = append(traceClientOpts, otlptracegrpc.WithInsecure())
-		}
-
-		traceClient := otlptracegrpc.NewClient(traceClientOpts...)
-
 		// trace exporter
-		traceExp, err = otlptrace.New(ctx, traceClient)
+		p.traceExp, err = newTraceExporter(ctx, cfg)
 		if err != nil {
-			log.Fatalf("failed to create otlp trace exporter: %s", err)
-			return nil
+			return fail(fmt.Errorf("failed to create otlp trace exporter: %w", err))
 		}
 
 		// trace processor
-		bsp := sdktrace.NewBatchSpanProcessor(traceExp)
+		bsp := sdktrace.NewBatchSpanProcessor(p.traceExp, cfg.batch.options()...)
+		processors := cfg.spanProcessors
+		if s, ok := cfg.sampler.(*dbSampler); ok {
+			// the deferred db spans are handed to the processors once sampled.
+			bsp = s.processor(bsp)
+			processors = make([]sdktrace.SpanProcessor, len(cfg.spanProcessors))
+			for i, sp := range cfg.spanProcessors {
+				processors[i] = s.processor(sp)
+			}
+		}
 
 		// trace provider
-		tracerProvider := cfg.sdkTracerProvider
+		tracerProvider = cfg.sdkTracerProvider
 		if tracerProvider == nil {
-			tracerProvider = sdktrace.NewTracerProvider(
-				sdktrace.WithSampler(sdktrace.AlwaysSample()),
+			sampler := cfg.sampler
+			if sampler == nil {
+				sampler = sdktrace.AlwaysSample()
+			}
+			tpOpts := []sdktrace.TracerProviderOption{
+				sdktrace.WithSampler(sampler),
 				sdktrace.WithResource(res),
 				sdktrace.WithSpanProcessor(bsp),
-			)
+			}
+			for _, sp := range processors {
+				tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(sp))
+			}
+			tracerProvider = sdktrace.NewTracerProvider(tpOpts...)
+			p.tracerProvider = tracerProvider
 		}
-
-		otel.SetTracerProvider(tracerProvider)
 	}
 
 	// Metrics
 	if cfg.enableMetrics {
-		metricsClientOpts := []otlpmetricgrpc.Option{
-			otlpmetricgrpc.WithAggregationSelector(sdkmetric.DefaultAggregationSelector),
-		}
-		if cfg.exportEndpoint != "" {
-			metricsClientOpts = append(metricsClientOpts, otlpmetricgrpc.WithEndpoint(cfg.exportEndpoint))
-		}
-		if len(cfg.exportHeaders) > 0 {
-			metricsClientOpts = append(metricsClientOpts, otlpmetricgrpc.WithHeaders(cfg.exportHeaders))
-		}
-		if cfg.exportInsecure {
-			metricsClientOpts = append(metricsClientOpts, otlpmetricgrpc.WithInsecure())
-		}
-
 		// metrics exporter
-		metricExp, err = otlpmetricgrpc.New(ctx,
-			metricsClientOpts...,
-		)
-		handleInitErr(err, "Failed to create the collector metric exporter")
+		p.metricsPusher, err = newMetricExporter(ctx, cfg)
+		if err != nil {
+			return fail(fmt.Errorf("failed to create the collector metric exporter: %w", err))
+		}
 
 		// metrics pusher
-		meterProvider := sdkmetric.NewMeterProvider(
-			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExp)),
+		var readerOpts []sdkmetric.PeriodicReaderOption
+		if cfg.metricExportInterval > 0 {
+			readerOpts = append(readerOpts, sdkmetric.WithInterval(cfg.metricExportInterval))
+		}
+		meterProvider = sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(p.metricsPusher, readerOpts...)),
 		)
-		otel.SetMeterProvider(meterProvider)
+		p.meterProvider = meterProvider
 
-		err = runtimemetrics.Start()
-		handleInitErr(err, "Failed to start runtime metrics collector")
+		if err = runtimemetrics.Start(runtimemetrics.WithMeterProvider(meterProvider)); err != nil {
+			return fail(fmt.Errorf("failed to start runtime metrics collector: %w", err))
+		}
 	}
 
-	return &defaultProvider{
-		traceExp:      traceExp,
-		metricsPusher: metricExp,
+	// propagator, only when configured, not to override the one of the application
+	if cfg.textMapPropagator != nil {
+		otel.SetTextMapPropagator(cfg.textMapPropagator)
+	}
+
+	if tracerProvider != nil {
+		otel.SetTracerProvider(tracerProvider)
+	}
+
+	if meterProvider != nil {
+		otel.SetMeterProvider(meterProvider)
 	}
+
+	return p, nil
 }
 
-func newResource(cfg *config) *sdkresource.Resource {
+func newResource(ctx context.Context, cfg *config) *sdkresource.Resource {
 	if cfg.resource != nil {
 		return cfg.resource
 	}
 
-	ctx := context.Background()
 	res, err := sdkresource.New(ctx,
 		sdkresource.WithFromEnv(),
 		sdkresource.WithProcess(),
@@ -157,9 +198,10 @@ func newResource(cfg *config) *sdkresource.Resource {
 		sdkresource.WithOS(),
 		sdkresource.WithContainer(),
 		sdkresource.WithProcessRuntimeName(),
-		sdkresource.WithSchemaURL(semconv.SchemaURL),
 		sdkresource.WithDetectors(cfg.resourceDetectors...),
 		sdkresource.WithAttributes(cfg.resourceAttributes...),
+		// the schema of the sdk detectors, a different one fails the merge of their resources.
+		sdkresource.WithSchemaURL(semconv.SchemaURL),
 	)
 
 	if err != nil {
@@ -169,9 +211,3 @@ func newResource(cfg *config) *sdkresource.Resource {
 
 	return res
 }
-
-func handleInitErr(err error, msg string) {
-	if err != nil {
-		log.Fatalf("%s: %+v", msg, err)
-	}
-}
@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanProcessor(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+
+	recorder := tracetest.NewSpanRecorder()
+
+	p, err := NewOpenTelemetryProviderE(context.TODO(),
+		WithEnableMetrics(false),
+		WithInsecure(),
+		WithExportEndpoint("localhost:4317"),
+		WithExportTimeout(10*time.Millisecond), // no collector
+		WithSpanProcessor(recorder),
+	)
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.TODO(), "statement")
+	span.End()
+
+	require.Equal(t, 1, len(recorder.Ended()))
+	require.Equal(t, "statement", recorder.Ended()[0].Name())
+
+	// the span processors are shut down with the provider, which no longer records.
+	require.NoError(t, p.Shutdown(context.TODO()))
+	_, span = otel.Tracer("test").Start(context.TODO(), "after shutdown")
+	require.False(t, span.IsRecording())
+}
+
+func TestSpanProcessorDBSampler(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+
+	recorder := tracetest.NewSpanRecorder()
+
+	p, err := NewOpenTelemetryProviderE(context.TODO(),
+		WithEnableMetrics(false),
+		WithInsecure(),
+		WithExportEndpoint("localhost:4317"),
+		WithExportTimeout(10*time.Millisecond), // no collector
+		WithSampler(DBSampler(0)),
+		WithSpanProcessor(recorder),
+	)
+	require.NoError(t, err)
+	defer p.Shutdown(context.TODO())
+
+	_, span := otel.Tracer("test").Start(context.TODO(), "statement",
+		trace.WithAttributes(semconv.DBSystemKey.String("sqlite3")))
+	span.SetAttributes(semconv.DBOperationKey.String("insert"))
+	span.End()
+
+	require.Equal(t, 1, len(recorder.Ended()))
+	require.True(t, recorder.Ended()[0].SpanContext().IsSampled())
+}
+
+func TestProviderDisabled(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "sdk disabled", env: map[string]string{"OTEL_SDK_DISABLED": "true"}},
+		{name: "exporters none", env: map[string]string{"OTEL_TRACES_EXPORTER": "none", "OTEL_METRICS_EXPORTER": "none"}},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+
+			// the callers may defer the Shutdown whatever the environment.
+			p := NewOpenTelemetryProvider()
+			require.NotNil(t, p)
+			require.NoError(t, p.Shutdown(context.TODO()))
+		})
+	}
+}
+
+var (
+	errs     = &errorHandler{}
+	errsOnce sync.Once
+)
+
+// errorHandler records the errors handed to otel.Handle.
+type errorHandler struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (h *errorHandler) Handle(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errs = append(h.errs, err)
+}
+
+// handled tells whether an error containing msg was handed to otel.Handle.
+func (h *errorHandler) handled(msg string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, err := range h.errs {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleErrors records the errors handed to otel.Handle, the handler is set once for all the tests
+// as the global one cannot be restored.
+func handleErrors() *errorHandler {
+	errsOnce.Do(func() { otel.SetErrorHandler(errs) })
+	errs.mu.Lock()
+	errs.errs = nil
+	errs.mu.Unlock()
+	return errs
+}
+
+func TestProviderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{name: "protocol", opt: WithExportProtocol("bogus")},
+		{name: "compression", opt: WithCompression("zstd")},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			tracerProvider, meterProvider := sdktrace.NewTracerProvider(), sdkmetric.NewMeterProvider()
+			defer otel.SetTracerProvider(otel.GetTracerProvider())
+			defer otel.SetMeterProvider(otel.GetMeterProvider())
+			otel.SetTracerProvider(tracerProvider)
+			otel.SetMeterProvider(meterProvider)
+
+			p, err := NewOpenTelemetryProviderE(context.TODO(), test.opt)
+			require.Error(t, err)
+			require.Nil(t, p)
+
+			// the global providers are left as they were.
+			require.Same(t, tracerProvider, otel.GetTracerProvider())
+			require.Same(t, meterProvider, otel.GetMeterProvider())
+		})
+	}
+}
+
+func TestProviderNoopFallback(t *testing.T) {
+	errs := handleErrors()
+
+	p, err := NewOpenTelemetryProviderE(context.TODO(), WithExportProtocol("bogus"), WithNoopFallback())
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	require.NoError(t, p.Shutdown(context.TODO()))
+
+	// the init error is handed to otel.Handle instead.
+	require.True(t, errs.handled("bogus"))
+}
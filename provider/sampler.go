@@ -0,0 +1,183 @@
+package provider
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var dbSlowQuery = attribute.Key("db.slow_query")
+
+// ParentBasedRatioSampler samples the ratio of the root spans, the other spans follow their parent.
+func ParentBasedRatioSampler(ratio float64) sdktrace.Sampler {
+	return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
+}
+
+// RateLimitedSampler samples at most perSecond traces per second with a token bucket,
+// which bursts up to one second of traces. The spans which are not roots follow their parent.
+func RateLimitedSampler(perSecond float64) sdktrace.Sampler {
+	return sdktrace.ParentBased(newRateLimitSampler(perSecond, time.Now))
+}
+
+type rateLimitSampler struct {
+	rate float64 // tokens per second
+	now  func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimitSampler(perSecond float64, now func() time.Time) *rateLimitSampler {
+	return &rateLimitSampler{rate: perSecond, now: now, tokens: burst(perSecond), last: now()}
+}
+
+// burst is the bucket capacity, one second of tokens but at least one.
+func burst(perSecond float64) float64 {
+	return math.Max(perSecond, 1)
+}
+
+func (s *rateLimitSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := sdktrace.SamplingResult{
+		Decision:   sdktrace.Drop,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+	if s.rate <= 0 {
+		return result
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.tokens = math.Min(s.tokens+now.Sub(s.last).Seconds()*s.rate, burst(s.rate))
+	s.last = now
+
+	if s.tokens >= 1 {
+		s.tokens--
+		result.Decision = sdktrace.RecordAndSample
+	}
+	return result
+}
+
+func (s *rateLimitSampler) Description() string {
+	return fmt.Sprintf("RateLimited{%g}", s.rate)
+}
+
+// DBSampler samples every span with an error, every slow query and every db span other than a select,
+// e.g. a transaction, and the fast successful selects at selectRatio. The other spans are sampled as their parent, the roots always.
+//
+// The outcome of a statement is only known at its end, so the spans with a db.system attribute at their start,
+// those of the tracing package, are recorded and decided once they end. It must be passed to WithSampler as is,
+// the provider wraps the batch span processor and the WithSpanProcessor ones with the processor deciding them,
+// the slow queries need tracing.WithSlowQueryThreshold.
+func DBSampler(selectRatio float64) sdktrace.Sampler {
+	return &dbSampler{
+		parent: sdktrace.ParentBased(sdktrace.AlwaysSample()),
+		ratio:  sdktrace.TraceIDRatioBased(selectRatio),
+	}
+}
+
+type dbSampler struct {
+	parent sdktrace.Sampler // the spans which are not db spans
+	ratio  sdktrace.Sampler // the fast successful selects
+}
+
+func (s *dbSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if !hasAttribute(p.Attributes, semconv.DBSystemKey) {
+		return s.parent.ShouldSample(p)
+	}
+
+	parent := trace.SpanFromContext(p.ParentContext)
+	result := sdktrace.SamplingResult{Tracestate: parent.SpanContext().TraceState()}
+
+	// a parent which is neither sampled nor recording is dropped, the children of a deferred db span,
+	// e.g. the statements of the tracing.BeginTx transaction span, are deferred as well.
+	if psc := parent.SpanContext(); psc.IsValid() && !psc.IsSampled() && !parent.IsRecording() {
+		result.Decision = sdktrace.Drop
+		return result
+	}
+
+	result.Decision = sdktrace.RecordOnly
+	return result
+}
+
+func (s *dbSampler) Description() string {
+	return fmt.Sprintf("DBSampler{%s,%s}", s.parent.Description(), s.ratio.Description())
+}
+
+// processor wraps the span processor with the end decision of the db spans.
+func (s *dbSampler) processor(next sdktrace.SpanProcessor) sdktrace.SpanProcessor {
+	return &dbSampleProcessor{SpanProcessor: next, ratio: s.ratio}
+}
+
+// dbSampleProcessor hands the db spans which are sampled at their end to the next processor as sampled spans.
+type dbSampleProcessor struct {
+	sdktrace.SpanProcessor
+	ratio sdktrace.Sampler
+}
+
+func (p *dbSampleProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.SpanProcessor.OnEnd(s)
+		return
+	}
+
+	if p.sample(s) {
+		p.SpanProcessor.OnEnd(sampledSpan{s})
+	}
+}
+
+func (p *dbSampleProcessor) sample(s sdktrace.ReadOnlySpan) bool {
+	attrs := s.Attributes()
+	if !hasAttribute(attrs, semconv.DBSystemKey) {
+		return false
+	}
+
+	if s.Status().Code == codes.Error {
+		return true
+	}
+	var operation string
+	for _, kv := range attrs {
+		switch kv.Key {
+		case dbSlowQuery:
+			if kv.Value.AsBool() {
+				return true
+			}
+		case semconv.DBOperationKey:
+			operation = kv.Value.AsString()
+		}
+	}
+	if operation != "select" {
+		return true
+	}
+
+	result := p.ratio.ShouldSample(sdktrace.SamplingParameters{TraceID: s.SpanContext().TraceID()})
+	return result.Decision == sdktrace.RecordAndSample
+}
+
+// sampledSpan is a span whose decision was deferred, reported as sampled to the exporters.
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s sampledSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
+
+func hasAttribute(attrs []attribute.KeyValue, key attribute.Key) bool {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRateLimitedSampler(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := newRateLimitSampler(2, func() time.Time { return now })
+
+	sampled := func() int {
+		n := 0
+		for i := 0; i < 10; i++ {
+			if s.ShouldSample(sdktrace.SamplingParameters{}).Decision == sdktrace.RecordAndSample {
+				n++
+			}
+		}
+		return n
+	}
+
+	require.Equal(t, 2, sampled())
+	require.Equal(t, 0, sampled())
+
+	now = now.Add(500 * time.Millisecond)
+	require.Equal(t, 1, sampled())
+
+	now = now.Add(time.Minute)
+	require.Equal(t, 2, sampled())
+}
+
+func TestDBSampler(t *testing.T) {
+	db := semconv.DBSystemKey.String("sqlite3")
+	selectOp := semconv.DBOperationKey.String("select")
+
+	tests := []struct {
+		name   string
+		parent bool // a sampled parent which is not a db span
+		attrs  []attribute.KeyValue
+		err    bool
+		want   bool
+	}{
+		{name: "not a db span", want: true},
+		{name: "fast select", attrs: []attribute.KeyValue{selectOp}},
+		{name: "fast select, sampled parent", parent: true, attrs: []attribute.KeyValue{selectOp}},
+		{name: "failing select", attrs: []attribute.KeyValue{selectOp}, err: true, want: true},
+		{name: "slow select", attrs: []attribute.KeyValue{selectOp, dbSlowQuery.Bool(true)}, want: true},
+		{name: "insert", attrs: []attribute.KeyValue{semconv.DBOperationKey.String("insert")}, want: true},
+		{name: "transaction", attrs: []attribute.KeyValue{}, want: true},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			s := DBSampler(0).(*dbSampler)
+			recorder := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(s), sdktrace.WithSpanProcessor(s.processor(recorder)))
+			tracer := tp.Tracer("test")
+
+			ctx := context.Background()
+			if test.parent {
+				var parent trace.Span
+				ctx, parent = tracer.Start(ctx, "request")
+				defer parent.End()
+			}
+
+			var opts []trace.SpanStartOption
+			if test.attrs != nil {
+				opts = append(opts, trace.WithAttributes(db))
+			}
+			_, span := tracer.Start(ctx, "statement", opts...)
+			span.SetAttributes(test.attrs...)
+			if test.err {
+				span.SetStatus(codes.Error, "failed")
+			}
+			span.End()
+
+			ended := recorder.Ended()
+			require.Equal(t, test.want, len(ended) == 1)
+			if test.want {
+				require.True(t, ended[0].SpanContext().IsSampled())
+			}
+		})
+	}
+}
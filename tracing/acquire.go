@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var dbConnWaitTime = attribute.Key("db.client.connections.wait_time")
+
+type acquisitionKey struct{}
+
+// acquisition is what the traced connections tell about the statements of a session started by Before.
+type acquisition struct {
+	mu    sync.Mutex
+	start time.Time // the Before of the session
+	first time.Time // the first statement run by a traced connection, zero when none
+	dsn   string    // the data source name of the pool which served the last statement, "" when not traced
+}
+
+func withAcquisition(ctx context.Context, start time.Time) context.Context {
+	return context.WithValue(ctx, acquisitionKey{}, &acquisition{start: start})
+}
+
+// acquired records that a connection of the pool of the dsn runs a statement of the ctx session.
+func acquired(ctx context.Context, dsn string) {
+	if a, ok := ctx.Value(acquisitionKey{}).(*acquisition); ok {
+		a.mu.Lock()
+		if a.first.IsZero() {
+			a.first = time.Now()
+		}
+		a.dsn = dsn
+		a.mu.Unlock()
+	}
+}
+
+// servedDSN returns the data source name of the pool which served the last statement of the ctx session,
+// "" when its connections are not traced.
+func servedDSN(ctx context.Context) string {
+	a, ok := ctx.Value(acquisitionKey{}).(*acquisition)
+	if !ok {
+		return ""
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dsn
+}
+
+// waitTime returns the time the ctx session waited for a connection, false when unknown.
+// database/sql does not time the acquisitions of a statement, so it is an upper bound measured from Before
+// until a traced connection, a new one once connected, first runs a statement of the session: the session work
+// between Before and its first statement is included. It is unknown unless the pool connections are traced,
+// see RegisterDriver.
+func waitTime(ctx context.Context) (time.Duration, bool) {
+	a, ok := ctx.Value(acquisitionKey{}).(*acquisition)
+	if !ok {
+		return 0, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.first.IsZero() {
+		return 0, false
+	}
+	return a.first.Sub(a.start), true
+}
+
+// acquisitionTimedOut tells whether the ctx session failed with err for want of a connection of db,
+// its deadline being exceeded before a traced connection of db ran a statement of the session.
+func acquisitionTimedOut(ctx context.Context, db *sql.DB, err error) bool {
+	if db == nil || !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if _, ok := db.Driver().(*tracedDriver); !ok {
+		return false
+	}
+	if _, ok := ctx.Value(acquisitionKey{}).(*acquisition); !ok {
+		return false
+	}
+	_, started := waitTime(ctx)
+	return !started
+}
@@ -0,0 +1,615 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	xormCore "xorm.io/core"
+)
+
+const (
+	ConnectAsSpanName  = "xorm:connect"  // open a new connection
+	PrepareAsSpanName  = "xorm:prepare"  // prepare a statement
+	BeginAsSpanName    = "xorm:begin"    // begin a transaction
+	CommitAsSpanName   = "xorm:commit"   // commit a transaction
+	RollbackAsSpanName = "xorm:rollback" // rollback a transaction
+
+	tracedDriverPrefix = "otel-"
+)
+
+var (
+	dbRowsReturned = attribute.Key("db.rows_returned")
+
+	errNamedArgs = errors.New("sql: driver does not support the use of Named Parameters")
+
+	registerDriverMu sync.Mutex
+)
+
+// RegisterDriver registers a traced wrapper of the database/sql driver registered as name,
+// and returns the wrapped driver name, which can be passed to xorm.NewEngine.
+// Every connect, prepare, statement, transaction and rows iteration gets a span,
+// but the statements traced by Before/After, which get no additional span.
+func RegisterDriver(name string, opts ...Option) (string, error) {
+	registerDriverMu.Lock()
+	defer registerDriverMu.Unlock()
+
+	tracedName := tracedDriverPrefix + name
+	for _, d := range sql.Drivers() {
+		if d == tracedName {
+			return "", fmt.Errorf("tracing: driver %q is already registered", tracedName)
+		}
+	}
+
+	db, err := sql.Open(name, "")
+	if err != nil {
+		return "", err
+	}
+	d := db.Driver()
+	_ = db.Close()
+
+	sql.Register(tracedName, &tracedDriver{Driver: d, name: name, p: newPlugin(opts...)})
+
+	// xorm picks the dialect by driver name.
+	if xd := xormCore.QueryDriver(name); xd != nil {
+		xormCore.RegisterDriver(tracedName, xd)
+	}
+
+	return tracedName, nil
+}
+
+type tracedDriver struct {
+	driver.Driver
+
+	name string // the wrapped driver name
+	p    *Plugin
+}
+
+func (d *tracedDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, d: d, dsn: dsn}, nil
+}
+
+func (d *tracedDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	if dc, ok := d.Driver.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return &tracedConnector{Connector: c, d: d, dsn: dsn}, nil
+	}
+
+	return &tracedConnector{Connector: dsnConnector{dsn: dsn, d: d.Driver}, d: d, dsn: dsn}, nil
+}
+
+// record creates a span from start to now for an action that has already happened.
+func (d *tracedDriver) record(ctx context.Context, spanName string, start time.Time, query string, args []driver.NamedValue, rowsAffected int64, err error) {
+	_, span := d.start(ctx, spanName, start)
+	defer span.End()
+
+	d.p.end(ctx, span, statement{
+		driverName:   d.name,
+		tableName:    dbTable(query),
+		rowsAffected: rowsAffected,
+		query:        query,
+		vars:         namedValues(args),
+		start:        start,
+		err:          err,
+		prepare:      spanName == PrepareAsSpanName,
+	})
+}
+
+func (d *tracedDriver) start(ctx context.Context, spanName string, start time.Time) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return d.p.tracer.Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindClient), trace.WithTimestamp(start),
+		d.p.startAttributes(d.name, nil))
+}
+
+type dsnConnector struct {
+	dsn string
+	d   driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.d.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.d
+}
+
+type tracedConnector struct {
+	driver.Connector
+
+	d   *tracedDriver
+	dsn string
+}
+
+func (c *tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	start := time.Now()
+	conn, err := c.Connector.Connect(ctx)
+	c.d.record(ctx, ConnectAsSpanName, start, "", nil, -1, err)
+	if err != nil {
+		return nil, err
+	}
+
+	acquired(ctx, c.dsn) // the new connection is part of the wait
+	return &tracedConn{Conn: conn, d: c.d, dsn: c.dsn}, nil
+}
+
+func (c *tracedConnector) Driver() driver.Driver {
+	return c.d
+}
+
+type tracedConn struct {
+	driver.Conn
+
+	d   *tracedDriver
+	dsn string // the data source name of the pool, see acquired
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	acquired(ctx, c.dsn)
+
+	var (
+		stmt  driver.Stmt
+		err   error
+		start = time.Now()
+	)
+	if cp, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = cp.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if !inStatement(ctx) {
+		c.d.record(ctx, PrepareAsSpanName, start, query, nil, -1, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, query: query, conn: c}, nil
+}
+
+func (c *tracedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	acquired(ctx, c.dsn)
+
+	var (
+		tx    driver.Tx
+		err   error
+		start = time.Now()
+	)
+	if cb, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = cb.BeginTx(ctx, opts)
+	} else {
+		// fallback for drivers without ConnBeginTx, as database/sql does.
+		tx, err = beginLegacy(ctx, c.Conn, opts)
+	}
+	c.d.record(ctx, BeginAsSpanName, start, "", nil, -1, err)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, ctx: ctx, d: c.d}, nil
+}
+
+// beginLegacy begins a transaction on a driver without ConnBeginTx, the options it cannot honor
+// are refused with the database/sql errors.
+func beginLegacy(ctx context.Context, conn driver.Conn, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return conn.Begin()
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	acquired(ctx, c.dsn)
+
+	var (
+		res   driver.Result
+		err   error
+		start = time.Now()
+	)
+	switch e := c.Conn.(type) {
+	case driver.ExecerContext:
+		res, err = e.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var vals []driver.Value
+		if vals, err = namedValueToValue(args); err == nil {
+			res, err = e.Exec(query, vals)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	if err == driver.ErrSkip {
+		// database/sql falls back to a prepared statement, which gets its own span.
+		return nil, err
+	}
+
+	if !inStatement(ctx) {
+		c.d.record(ctx, operationSpanName(dbOperation(query)), start, query, args, rowsAffected(res), err)
+	}
+	return res, err
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	acquired(ctx, c.dsn)
+
+	var (
+		rows  driver.Rows
+		err   error
+		start = time.Now()
+	)
+	switch q := c.Conn.(type) {
+	case driver.QueryerContext:
+		rows, err = q.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var vals []driver.Value
+		if vals, err = namedValueToValue(args); err == nil {
+			rows, err = q.Query(query, vals)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+
+	return c.d.rows(ctx, start, query, args, rows, err)
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	acquired(ctx, c.dsn) // a pool connection is reset before its reuse
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// rows starts the rows span, which ends when the rows are closed.
+func (d *tracedDriver) rows(ctx context.Context, start time.Time, query string, args []driver.NamedValue, rows driver.Rows, err error) (driver.Rows, error) {
+	if err != nil {
+		if inStatement(ctx) {
+			return nil, err
+		}
+		d.record(ctx, operationSpanName(dbOperation(query)), start, query, args, -1, err)
+		return nil, err
+	}
+
+	if inStatement(ctx) {
+		return rows, nil
+	}
+
+	ctx, span := d.start(ctx, operationSpanName(dbOperation(query)), start)
+	return &tracedRows{Rows: rows, ctx: ctx, span: span, start: start, query: query, args: args, d: d}, nil
+}
+
+type tracedStmt struct {
+	driver.Stmt
+
+	query string
+	conn  *tracedConn // the connection which prepared the statement
+}
+
+func (s *tracedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valueToNamedValue(args))
+}
+
+func (s *tracedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valueToNamedValue(args))
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	acquired(ctx, s.conn.dsn)
+
+	var (
+		res   driver.Result
+		err   error
+		start = time.Now()
+	)
+	if se, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = se.ExecContext(ctx, args)
+	} else {
+		var vals []driver.Value
+		if vals, err = namedValueToValue(args); err == nil {
+			res, err = s.Stmt.Exec(vals)
+		}
+	}
+
+	if !inStatement(ctx) {
+		s.conn.d.record(ctx, operationSpanName(dbOperation(s.query)), start, s.query, args, rowsAffected(res), err)
+	}
+	return res, err
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	acquired(ctx, s.conn.dsn)
+
+	var (
+		rows  driver.Rows
+		err   error
+		start = time.Now()
+	)
+	if sq, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = sq.QueryContext(ctx, args)
+	} else {
+		var vals []driver.Value
+		if vals, err = namedValueToValue(args); err == nil {
+			rows, err = s.Stmt.Query(vals)
+		}
+	}
+
+	return s.conn.d.rows(ctx, start, s.query, args, rows, err)
+}
+
+// CheckNamedValue checks the argument as database/sql does for the unwrapped statement: with the NamedValueChecker
+// of the statement, else of its connection, then with the ColumnConverter of the statement when skipped.
+func (s *tracedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	nc, ok := s.Stmt.(driver.NamedValueChecker)
+	if !ok {
+		nc, ok = s.conn.Conn.(driver.NamedValueChecker)
+	}
+	if ok {
+		if err := nc.CheckNamedValue(nv); err != driver.ErrSkip {
+			return err
+		}
+	}
+
+	if cc, ok := s.Stmt.(driver.ColumnConverter); ok {
+		return convertColumn(cc, s.Stmt.NumInput(), nv)
+	}
+	return driver.ErrSkip
+}
+
+// convertColumn converts the argument with the ColumnConverter of a statement of want inputs, as the database/sql
+// ccChecker: the driver.Valuer is called first, the arguments beyond the inputs, all when unknown, are left as is.
+func convertColumn(cc driver.ColumnConverter, want int, nv *driver.NamedValue) error {
+	index := nv.Ordinal - 1
+	if want <= index {
+		return nil
+	}
+
+	if vr, ok := nv.Value.(driver.Valuer); ok {
+		v, err := callValuer(vr)
+		if err != nil {
+			return err
+		}
+		if !driver.IsValue(v) {
+			return fmt.Errorf("non-subset type %T returned from Value", v)
+		}
+		nv.Value = v
+	}
+
+	arg := nv.Value
+	v, err := cc.ColumnConverter(index).ConvertValue(arg)
+	if err != nil {
+		return err
+	}
+	if !driver.IsValue(v) {
+		return fmt.Errorf("driver ColumnConverter error converted %T to unsupported type %T", arg, v)
+	}
+	nv.Value = v
+	return nil
+}
+
+// callValuer calls the Value of vr, a nil pointer whose Value has a value receiver being a NULL as in database/sql.
+func callValuer(vr driver.Valuer) (driver.Value, error) {
+	if rv := reflect.ValueOf(vr); rv.Kind() == reflect.Pointer && rv.IsNil() &&
+		rv.Type().Elem().Implements(reflect.TypeOf((*driver.Valuer)(nil)).Elem()) {
+		return nil, nil
+	}
+	return vr.Value()
+}
+
+type tracedTx struct {
+	driver.Tx
+
+	ctx context.Context // the BeginTx ctx, Commit and Rollback have none
+	d   *tracedDriver
+}
+
+func (t *tracedTx) Commit() error {
+	start := time.Now()
+	err := t.Tx.Commit()
+	t.d.record(t.ctx, CommitAsSpanName, start, "", nil, -1, err)
+	return err
+}
+
+func (t *tracedTx) Rollback() error {
+	start := time.Now()
+	err := t.Tx.Rollback()
+	t.d.record(t.ctx, RollbackAsSpanName, start, "", nil, -1, err)
+	return err
+}
+
+type tracedRows struct {
+	driver.Rows
+
+	ctx   context.Context
+	span  trace.Span
+	start time.Time
+	query string
+	args  []driver.NamedValue
+	count int64
+	err   error
+	d     *tracedDriver
+}
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch err {
+	case nil:
+		r.count++
+	case io.EOF:
+	default:
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	if r.err == nil {
+		r.err = err
+	}
+
+	r.span.SetAttributes(dbRowsReturned.Int64(r.count))
+	r.d.p.end(r.ctx, r.span, statement{
+		driverName:   r.d.name,
+		tableName:    dbTable(r.query),
+		rowsAffected: -1,
+		query:        r.query,
+		vars:         namedValues(r.args),
+		start:        r.start,
+		err:          r.err,
+	})
+	r.span.End()
+	return err
+}
+
+func (r *tracedRows) HasNextResultSet() bool {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return rs.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *tracedRows) NextResultSet() error {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return rs.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *tracedRows) ColumnTypeScanType(index int) reflect.Type {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return ct.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+func (r *tracedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return ct.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *tracedRows) ColumnTypeLength(index int) (int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return ct.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *tracedRows) ColumnTypeNullable(index int) (bool, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return ct.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *tracedRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return ct.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+func rowsAffected(res driver.Result) int64 {
+	if res == nil {
+		return -1
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+func namedValues(args []driver.NamedValue) []any {
+	if len(args) == 0 {
+		return nil
+	}
+	vars := make([]any, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			vars[i] = sql.Named(arg.Name, arg.Value) // replaces the named placeholder of the statement
+		} else {
+			vars[i] = arg.Value
+		}
+	}
+	return vars
+}
+
+func namedValueToValue(args []driver.NamedValue) ([]driver.Value, error) {
+	vals := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errNamedArgs
+		}
+		vals[i] = arg.Value
+	}
+	return vals, nil
+}
+
+func valueToNamedValue(args []driver.Value) []driver.NamedValue {
+	nvs := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nvs[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nvs
+}
+
+// tracedDriverName returns the wrapped driver name of a driver registered by RegisterDriver.
+func tracedDriverName(driverName string) string {
+	return strings.TrimPrefix(driverName, tracedDriverPrefix)
+}
@@ -0,0 +1,307 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-xorm/xorm"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	xormCore "xorm.io/core"
+)
+
+func TestRegisterDriver(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	base := registerSQLite()
+	name, err := RegisterDriver(base, WithTracerProvider(provider), WithDriverName(xormCore.SQLITE))
+	require.NoError(t, err)
+	require.Equal(t, "otel-"+base, name)
+
+	_, err = RegisterDriver(base)
+	require.Error(t, err)
+
+	db, err := xorm.NewEngine(name, "file:driver?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+
+	_, err = db.Context(ctx).Exec("CREATE TABLE driver_user (id integer primary key autoincrement, name text)")
+	require.NoError(t, err)
+
+	session := db.NewSession().Context(ctx)
+	require.NoError(t, session.Begin())
+	_, err = session.Table("driver_user").Insert(&hookUser{Name: "foo"})
+	require.NoError(t, err)
+	require.NoError(t, session.Commit())
+	session.Close()
+
+	var users []hookUser
+	require.NoError(t, db.Context(ctx).Table("driver_user").Where("name = ?", "foo").Find(&users))
+	require.Equal(t, 1, len(users))
+
+	parent.End()
+
+	names := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range sr.Ended() {
+		names[s.Name()] = s
+		if s.Name() != "parent" && s.Name() != ConnectAsSpanName {
+			require.Equal(t, parent.SpanContext().TraceID(), s.SpanContext().TraceID())
+		}
+	}
+
+	for _, n := range []string{ConnectAsSpanName, BeginAsSpanName, CreatAsSpanName, CommitAsSpanName, QueryAsSpanName} {
+		_, ok := names[n]
+		require.True(t, ok, n)
+	}
+
+	m := attrMap(names[QueryAsSpanName].Attributes())
+
+	sys, ok := m[semconv.DBSystemKey]
+	require.True(t, ok)
+	require.Equal(t, xormCore.SQLITE, sys.AsString())
+
+	table, ok := m[semconv.DBSQLTableKey]
+	require.True(t, ok)
+	require.Equal(t, "driver_user", table.AsString())
+
+	returned, ok := m[dbRowsReturned]
+	require.True(t, ok)
+	require.Equal(t, int64(1), returned.AsInt64())
+
+	m = attrMap(names[CreatAsSpanName].Attributes())
+
+	affected, ok := m[dbRowsAffected]
+	require.True(t, ok)
+	require.Equal(t, int64(1), affected.AsInt64())
+}
+
+func TestRegisterDriverNamedArgs(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	name, err := RegisterDriver(registerSQLite(), WithTracerProvider(provider), WithDriverName(xormCore.SQLITE), WithoutMetrics())
+	require.NoError(t, err)
+
+	db, err := sql.Open(name, "file:named?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("SELECT @name, :id", sql.Named("name", "foo"), sql.Named("id", 1))
+	require.NoError(t, err)
+
+	var stmt string
+	for _, s := range sr.Ended() {
+		if s.Name() == QueryAsSpanName {
+			stmt = attrMap(s.Attributes())[semconv.DBStatementKey].AsString()
+		}
+	}
+	require.Equal(t, "SELECT 'foo', 1", stmt)
+}
+
+// fakeDriver is a driver whose connections, opened in connect, accept the custom arguments and prepare
+// the stmt statements.
+type fakeDriver struct {
+	stmt    driver.Stmt
+	connect time.Duration
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	time.Sleep(d.connect)
+	return fakeConn{stmt: d.stmt}, nil
+}
+
+type custom struct{ n int64 }
+
+type fakeConn struct {
+	stmt driver.Stmt
+}
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return c.stmt, nil }
+func (fakeConn) Close() error                          { return nil }
+func (fakeConn) Begin() (driver.Tx, error)             { return legacyTx{}, nil }
+
+func (fakeConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if c, ok := nv.Value.(custom); ok {
+		nv.Value = c.n
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+// fakeStmt is a statement of inputs arguments, the arguments it runs are recorded into args.
+type fakeStmt struct {
+	inputs int
+	args   *[]driver.Value
+}
+
+func (fakeStmt) Close() error    { return nil }
+func (s fakeStmt) NumInput() int { return s.inputs }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	*s.args = args
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error) { return nil, driver.ErrSkip }
+
+// int64Stmt is a fakeStmt whose ColumnConverter only accepts the int64.
+type int64Stmt struct {
+	fakeStmt
+}
+
+func (int64Stmt) ColumnConverter(int) driver.ValueConverter { return int64Converter{} }
+
+type int64Converter struct{}
+
+func (int64Converter) ConvertValue(v any) (driver.Value, error) {
+	if n, ok := v.(int64); ok {
+		return n, nil
+	}
+	return nil, fmt.Errorf("not an int64: %T", v)
+}
+
+type valuer struct{ n int64 }
+
+func (v valuer) Value() (driver.Value, error) { return v.n, nil }
+
+func TestRegisterDriverCheckNamedValue(t *testing.T) {
+	var args []driver.Value
+
+	tests := []struct {
+		name string
+		stmt driver.Stmt
+		arg  any
+		want driver.Value
+	}{
+		{name: "conn checker", stmt: fakeStmt{inputs: 1, args: &args}, arg: custom{n: 3}, want: int64(3)},
+		{name: "column converter valuer", stmt: int64Stmt{fakeStmt{inputs: 1, args: &args}}, arg: valuer{n: 3}, want: int64(3)},
+		{name: "column converter unknown inputs", stmt: int64Stmt{fakeStmt{inputs: -1, args: &args}}, arg: "foo", want: "foo"},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			base := fmt.Sprintf("fake-%d", testDrivers.Add(1))
+			sql.Register(base, fakeDriver{stmt: test.stmt})
+
+			name, err := RegisterDriver(base, WithoutMetrics())
+			require.NoError(t, err)
+
+			db, err := sql.Open(name, "")
+			require.NoError(t, err)
+			defer db.Close()
+
+			_, err = db.Exec("UPDATE t SET a = ?", test.arg)
+			require.NoError(t, err)
+			require.Equal(t, []driver.Value{test.want}, args)
+		})
+	}
+}
+
+func TestRegisterDriverOperationMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	base := fmt.Sprintf("fake-%d", testDrivers.Add(1))
+	var args []driver.Value
+	sql.Register(base, fakeDriver{stmt: fakeStmt{inputs: 1, args: &args}})
+
+	name, err := RegisterDriver(base, WithDriverName(xormCore.SQLITE), WithMeterProvider(meterProvider),
+		WithSlowQueryThreshold(time.Nanosecond))
+	require.NoError(t, err)
+
+	db, err := sql.Open(name, "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	// the connect, prepare, begin and commit run no operation, the prepared exec is counted once.
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec("UPDATE t SET a = ?", 1)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.TODO(), &rm))
+
+	counts := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				continue
+			}
+			for _, dp := range sum.DataPoints {
+				operation, _ := dp.Attributes.Value(semconv.DBOperationKey)
+				require.Equal(t, "update", operation.AsString())
+				counts[m.Name] += dp.Value
+			}
+		}
+	}
+	require.Equal(t, map[string]int64{"db.client.operations": 1, "db.client.operations.slow": 1}, counts)
+}
+
+var (
+	testDrivers atomic.Int64
+
+	tracedSQLiteOnce sync.Once
+)
+
+// tracedSQLite registers the traced sqlite3 driver once, whose name tells the db.system of its engines,
+// its spans go to the global tracer provider.
+func tracedSQLite(t *testing.T) string {
+	tracedSQLiteOnce.Do(func() {
+		_, err := RegisterDriver(xormCore.SQLITE, WithoutMetrics())
+		require.NoError(t, err)
+	})
+	return tracedDriverPrefix + xormCore.SQLITE
+}
+
+// registerSQLite registers the sqlite3 driver under a new name, as the drivers cannot be unregistered
+// and the tests may run more than once, e.g. with -count=2.
+func registerSQLite() string {
+	name := fmt.Sprintf("sqlite3-%d", testDrivers.Add(1))
+	sql.Register(name, &sqlite3.SQLiteDriver{})
+	xormCore.RegisterDriver(name, xormCore.QueryDriver(xormCore.SQLITE))
+	return name
+}
+
+// legacyConn is a driver connection without ConnBeginTx.
+type legacyConn struct{}
+
+func (legacyConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (legacyConn) Close() error                        { return nil }
+func (legacyConn) Begin() (driver.Tx, error)           { return legacyTx{}, nil }
+
+type legacyTx struct{}
+
+func (legacyTx) Commit() error   { return nil }
+func (legacyTx) Rollback() error { return nil }
+
+func TestBeginTxLegacyDriver(t *testing.T) {
+	c := &tracedConn{Conn: legacyConn{}, d: &tracedDriver{p: newPlugin(WithoutMetrics())}}
+
+	_, err := c.BeginTx(context.TODO(), driver.TxOptions{})
+	require.NoError(t, err)
+
+	_, err = c.BeginTx(context.TODO(), driver.TxOptions{Isolation: driver.IsolationLevel(sql.LevelSerializable)})
+	require.Error(t, err)
+
+	_, err = c.BeginTx(context.TODO(), driver.TxOptions{ReadOnly: true})
+	require.Error(t, err)
+}
@@ -0,0 +1,201 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+
+	"github.com/go-xorm/xorm"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// The error.type values of the well-known DB errors.
+const (
+	ErrorTypeDeadlock        = "deadlock"
+	ErrorTypeUniqueViolation = "unique_violation"
+	ErrorTypeTimeout         = "timeout"
+	ErrorTypeCanceled        = "canceled"
+)
+
+var dbResponseStatusCode = attribute.Key("db.response.status_code")
+
+// ErrorClass is the classification of a DB error.
+type ErrorClass struct {
+	// StatusCode is the db.response.status_code, e.g. the MySQL error number 1062,
+	// the Postgres SQLSTATE 23505 or the sqlite extended result code 2067.
+	StatusCode string
+	// Type is the error.type, e.g. ErrorTypeUniqueViolation, the Go type of the error by default.
+	Type string
+	// Expected errors are not recorded as span errors, e.g. sql.ErrNoRows.
+	Expected bool
+}
+
+// ErrorClassifier classifies the non-nil errors of the traced statements,
+// it may wrap ClassifyError to only change some of them.
+type ErrorClassifier func(err error) ErrorClass
+
+// ClassifyError is the default ErrorClassifier, it knows the errors of the MySQL, Postgres (lib/pq, pgx),
+// MSSQL and sqlite drivers, the context errors and the network timeouts.
+func ClassifyError(err error) ErrorClass {
+	switch err {
+	case xorm.ErrNotExist,
+		driver.ErrSkip,
+		io.EOF, // end of rows iterator
+		sql.ErrNoRows:
+		return ErrorClass{Type: fmt.Sprintf("%T", err), Expected: true}
+	}
+
+	class := driverErrorClass(err)
+	if class.Type == "" {
+		var netErr net.Error
+		switch {
+		case errors.Is(err, context.Canceled):
+			class.Type = ErrorTypeCanceled
+		case errors.Is(err, context.DeadlineExceeded),
+			errors.As(err, &netErr) && netErr.Timeout():
+			class.Type = ErrorTypeTimeout
+		default:
+			class.Type = fmt.Sprintf("%T", err)
+		}
+	}
+
+	return class
+}
+
+// sqlStateError is implemented by the Postgres errors of lib/pq and pgx.
+type sqlStateError interface {
+	SQLState() string
+}
+
+// sqlErrorNumber is implemented by the MSSQL errors of go-mssqldb.
+type sqlErrorNumber interface {
+	SQLErrorNumber() int32
+}
+
+// driverErrorClass returns the class of the driver error wrapped by err, the drivers are matched
+// without importing them, so a zero class is returned for an unknown driver.
+func driverErrorClass(err error) ErrorClass {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch e := e.(type) {
+		case sqlStateError:
+			code := e.SQLState()
+			return ErrorClass{StatusCode: code, Type: postgresErrorType(code)}
+		case sqlErrorNumber:
+			n := e.SQLErrorNumber()
+			return ErrorClass{StatusCode: strconv.Itoa(int(n)), Type: mssqlErrorType(n)}
+		}
+
+		v := reflect.Indirect(reflect.ValueOf(e))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+
+		switch v.Type().PkgPath() + "." + v.Type().Name() {
+		case "github.com/go-sql-driver/mysql.MySQLError":
+			n := v.FieldByName("Number").Uint()
+			return ErrorClass{StatusCode: strconv.FormatUint(n, 10), Type: mysqlErrorType(n)}
+		case "github.com/mattn/go-sqlite3.Error":
+			code, extended := v.FieldByName("Code").Int(), v.FieldByName("ExtendedCode").Int()
+			if extended == 0 {
+				extended = code
+			}
+			return ErrorClass{StatusCode: strconv.FormatInt(extended, 10), Type: sqliteErrorType(code, extended)}
+		}
+	}
+
+	return ErrorClass{}
+}
+
+func mysqlErrorType(number uint64) string {
+	switch number {
+	case 1213: // ER_LOCK_DEADLOCK
+		return ErrorTypeDeadlock
+	case 1062, 1586: // ER_DUP_ENTRY, ER_DUP_ENTRY_WITH_KEY_NAME
+		return ErrorTypeUniqueViolation
+	case 1205, 3024: // ER_LOCK_WAIT_TIMEOUT, ER_QUERY_TIMEOUT
+		return ErrorTypeTimeout
+	case 1317: // ER_QUERY_INTERRUPTED
+		return ErrorTypeCanceled
+	default:
+		return ""
+	}
+}
+
+func postgresErrorType(sqlState string) string {
+	switch sqlState {
+	case "40P01": // deadlock_detected
+		return ErrorTypeDeadlock
+	case "23505": // unique_violation
+		return ErrorTypeUniqueViolation
+	case "57014", "55P03": // query_canceled by statement_timeout, lock_not_available
+		return ErrorTypeTimeout
+	default:
+		return ""
+	}
+}
+
+func mssqlErrorType(number int32) string {
+	switch number {
+	case 1205: // deadlock victim
+		return ErrorTypeDeadlock
+	case 2601, 2627: // duplicate key, unique constraint violation
+		return ErrorTypeUniqueViolation
+	case 1222: // lock request time out
+		return ErrorTypeTimeout
+	default:
+		return ""
+	}
+}
+
+func sqliteErrorType(code, extended int64) string {
+	switch {
+	case extended == 2067 || extended == 1555: // SQLITE_CONSTRAINT_UNIQUE, SQLITE_CONSTRAINT_PRIMARYKEY
+		return ErrorTypeUniqueViolation
+	case code == 5: // SQLITE_BUSY, the busy timeout expired
+		return ErrorTypeTimeout
+	case code == 9: // SQLITE_INTERRUPT
+		return ErrorTypeCanceled
+	default:
+		return ""
+	}
+}
+
+// classify returns the class of err, nil errors have a zero class.
+// A failure of a statement whose ctx is done is classified as canceled or timeout.
+func (p *Plugin) classify(ctx context.Context, err error) ErrorClass {
+	if err == nil {
+		return ErrorClass{}
+	}
+
+	var class ErrorClass
+	if p.errorClassifier != nil {
+		class = p.errorClassifier(err)
+	} else {
+		class = ClassifyError(err)
+	}
+
+	if !class.Expected && class.Type != ErrorTypeCanceled && class.Type != ErrorTypeTimeout {
+		switch ctx.Err() {
+		case context.Canceled:
+			class.Type = ErrorTypeCanceled
+		case context.DeadlineExceeded:
+			class.Type = ErrorTypeTimeout
+		}
+	}
+	if p.canceledNotError && class.Type == ErrorTypeCanceled {
+		class.Expected = true
+	}
+
+	return class
+}
+
+// isError reports whether err should be recorded as a span error.
+func (p *Plugin) isError(ctx context.Context, err error) bool {
+	return err != nil && !p.classify(ctx, err).Expected
+}
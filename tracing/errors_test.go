@@ -0,0 +1,146 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-xorm/xorm"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	xormCore "xorm.io/core"
+)
+
+type pgError struct{ code string }
+
+func (e *pgError) Error() string    { return "pq: " + e.code }
+func (e *pgError) SQLState() string { return e.code }
+
+type mssqlError struct{ number int32 }
+
+func (e mssqlError) Error() string         { return fmt.Sprintf("mssql: %d", e.number) }
+func (e mssqlError) SQLErrorNumber() int32 { return e.number }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"no rows", sql.ErrNoRows, ErrorClass{Type: "*errors.errorString", Expected: true}},
+		{"canceled", fmt.Errorf("query: %w", context.Canceled), ErrorClass{Type: ErrorTypeCanceled}},
+		{"deadline", context.DeadlineExceeded, ErrorClass{Type: ErrorTypeTimeout}},
+		{"postgres deadlock", &pgError{"40P01"}, ErrorClass{StatusCode: "40P01", Type: ErrorTypeDeadlock}},
+		{"postgres unique", fmt.Errorf("insert: %w", &pgError{"23505"}), ErrorClass{StatusCode: "23505", Type: ErrorTypeUniqueViolation}},
+		{"postgres other", &pgError{"42P01"}, ErrorClass{StatusCode: "42P01", Type: "*tracing.pgError"}},
+		{"mssql deadlock", mssqlError{1205}, ErrorClass{StatusCode: "1205", Type: ErrorTypeDeadlock}},
+		{"sqlite unique", sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique},
+			ErrorClass{StatusCode: "2067", Type: ErrorTypeUniqueViolation}},
+		{"sqlite busy", sqlite3.Error{Code: sqlite3.ErrBusy}, ErrorClass{StatusCode: "5", Type: ErrorTypeTimeout}},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.want, ClassifyError(test.err))
+		})
+	}
+}
+
+func TestErrorClassifier(t *testing.T) {
+	db, err := xorm.NewEngine(xormCore.SQLITE, "file:classifier?mode=memory&cache=shared")
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE classifier_user (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+
+	expectUnique := func(err error) ErrorClass {
+		class := ClassifyError(err)
+		class.Expected = class.Type == ErrorTypeUniqueViolation
+		return class
+	}
+
+	for _, opts := range [][]Option{nil, {WithErrorClassifier(expectUnique)}} {
+		sr := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+		p := newPlugin(append(opts, WithTracerProvider(provider), WithoutMetrics())...)
+
+		ctx, session := p.Before(context.TODO(), CreatAsSpanName, db)
+		_, err = session.Exec("INSERT OR IGNORE INTO classifier_user (id) VALUES (1)")
+		require.NoError(t, err)
+		_, err = session.Exec("INSERT INTO classifier_user (id) VALUES (1)")
+		p.After(ctx, db.DriverName(), "classifier_user", -1, session, err)
+		require.Error(t, err)
+
+		spans := sr.Ended()
+		require.Equal(t, 1, len(spans))
+
+		m := attrMap(spans[0].Attributes())
+		if opts != nil {
+			require.Equal(t, codes.Ok, spans[0].Status().Code)
+			require.NotContains(t, m, errorType)
+			continue
+		}
+
+		require.Equal(t, codes.Error, spans[0].Status().Code)
+		require.Equal(t, ErrorTypeUniqueViolation, m[errorType].AsString())
+		require.Equal(t, "1555", m[dbResponseStatusCode].AsString())
+	}
+}
+
+func TestContextErrors(t *testing.T) {
+	db, err := xorm.NewEngine(xormCore.SQLITE, "file::memory:?cache=shared")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		cancel   bool
+		opts     []Option
+		code     codes.Code
+		wantType string
+	}{
+		{name: "deadline", code: codes.Ok},
+		{name: "canceled", cancel: true, code: codes.Error, wantType: ErrorTypeCanceled},
+		{name: "canceled not error", cancel: true, opts: []Option{WithoutCanceledErrors()}, code: codes.Ok},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+			p := newPlugin(append(test.opts, WithTracerProvider(provider), WithoutMetrics())...)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			if test.cancel {
+				cancel()
+			}
+
+			ctx, session := p.Before(ctx, RawAsSpanName, db)
+			_, err := session.Exec("SELECT 42")
+			p.After(ctx, db.DriverName(), "", -1, session, err)
+			require.Equal(t, test.cancel, err != nil)
+
+			spans := sr.Ended()
+			require.Equal(t, 1, len(spans))
+			require.Equal(t, test.code, spans[0].Status().Code)
+
+			m := attrMap(spans[0].Attributes())
+
+			remaining, ok := m[dbDeadlineRemaining]
+			require.True(t, ok)
+			require.InDelta(t, time.Minute.Seconds(), remaining.AsFloat64(), 1)
+
+			typ, ok := m[errorType]
+			require.Equal(t, test.wantType != "", ok)
+			require.Equal(t, test.wantType, typ.AsString())
+		})
+	}
+}
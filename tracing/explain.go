@@ -0,0 +1,174 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExplainAsSpanName = "xorm:explain" // the EXPLAIN of a slow SELECT, a child of its span
+
+	queryPlanEventName = "query plan"
+
+	defaultExplainInterval = time.Second
+	defaultExplainTimeout  = time.Second
+)
+
+var dbQueryPlan = attribute.Key("db.query.plan")
+
+// ExplainConfig configures the query plan capture of the slow SELECT statements.
+type ExplainConfig struct {
+	// Threshold is the duration from which a SELECT is explained.
+	Threshold time.Duration
+	// SampleRatio is the ratio of the slow SELECTs which are explained, 0 explains them all.
+	SampleRatio float64
+	// Interval is the minimum delay between two EXPLAIN, 1s by default.
+	Interval time.Duration
+	// Timeout bounds the EXPLAIN execution, 1s by default.
+	Timeout time.Duration
+}
+
+// explainer runs the EXPLAIN of the slow SELECT statements, at most once per interval.
+type explainer struct {
+	cfg ExplainConfig
+}
+
+func newExplainer(cfg ExplainConfig) *explainer {
+	if cfg.SampleRatio <= 0 || cfg.SampleRatio > 1 {
+		cfg.SampleRatio = 1
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultExplainInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultExplainTimeout
+	}
+	return &explainer{cfg: cfg}
+}
+
+// allow reports whether a SELECT lasting duration is sampled and within the rate limit,
+// next is the unix nano time from which the next EXPLAIN may run, shared by the call-scoped plugins.
+func (e *explainer) allow(duration time.Duration, next *atomic.Int64) bool {
+	if duration < 0 || duration < e.cfg.Threshold {
+		return false
+	}
+	if e.cfg.SampleRatio < 1 && rand.Float64() >= e.cfg.SampleRatio {
+		return false
+	}
+
+	now := time.Now().UnixNano()
+	n := next.Load()
+	if now < n {
+		return false
+	}
+	return next.CompareAndSwap(n, now+int64(e.cfg.Interval))
+}
+
+// explain runs the EXPLAIN of the statement on db in the background, so that the caller never waits
+// for a pool connection, and adds the plan as an event of an ExplainAsSpanName child of the statement span.
+func (p *Plugin) explain(parent trace.SpanContext, db *sql.DB, sys attribute.KeyValue, stmt statement) {
+	prefix := explainPrefix(sys.Value.AsString())
+	if prefix == "" {
+		return
+	}
+
+	p.explains.Add(1)
+	go func() {
+		defer p.explains.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), p.explainer.cfg.Timeout)
+		defer cancel()
+
+		query := prefix + stmt.query
+		ctx, span := p.tracer.Start(trace.ContextWithSpanContext(ctx, parent), ExplainAsSpanName,
+			trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(sys, semconv.DBStatementKey.String(query)))
+		defer span.End()
+
+		plan, err := queryPlan(ctx, db, query, stmt.vars...)
+		if err != nil {
+			span.RecordError(err)
+			return
+		}
+
+		span.AddEvent(queryPlanEventName, trace.WithAttributes(
+			semconv.DBStatementKey.String(query),
+			dbQueryPlan.String(plan),
+		))
+	}()
+}
+
+// explainable reports whether the plan of the statement may be recorded: neither in a transaction,
+// whose connection the EXPLAIN would wait for, nor with a sanitizer, WithoutQueryVariables or a redaction policy,
+// as the plans may quote the query variables.
+func (p *Plugin) explainable(ctx context.Context, stmt statement) bool {
+	return p.explainer != nil && stmt.db != nil && !inTransaction(ctx) &&
+		p.sanitizer == nil && !p.excludeQueryVars && p.redactionPolicy == nil &&
+		dbOperation(stmt.query) == "select"
+}
+
+// explainPrefix returns the EXPLAIN statement prefix of the db.system, or "" if unsupported.
+func explainPrefix(system string) string {
+	switch system {
+	case "sqlite3", "sqlite":
+		return "EXPLAIN QUERY PLAN "
+	case semconv.DBSystemMySQL.Value.AsString(), semconv.DBSystemMariaDB.Value.AsString():
+		return "EXPLAIN FORMAT=JSON "
+	case semconv.DBSystemMSSQL.Value.AsString(), semconv.DBSystemOracle.Value.AsString():
+		// SHOWPLAN and EXPLAIN PLAN FOR need a session setting or a plan table.
+		return ""
+	default:
+		return "EXPLAIN "
+	}
+}
+
+// queryPlan returns the rows of the EXPLAIN, one line per row with tab separated columns.
+func queryPlan(ctx context.Context, db *sql.DB, query string, args ...any) (string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		b      strings.Builder
+		values = make([]sql.NullString, len(columns))
+		dest   = make([]any, len(columns))
+	)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		for i, v := range values {
+			if i > 0 {
+				b.WriteByte('\t')
+			}
+			if v.Valid {
+				b.WriteString(v.String)
+			} else {
+				b.WriteString("NULL")
+			}
+		}
+	}
+
+	return b.String(), rows.Err()
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/go-xorm/xorm"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// The db.instance.role values of the engine group members.
+const (
+	RolePrimary = "primary"
+	RoleReplica = "replica"
+)
+
+var dbInstanceRole = attribute.Key("db.instance.role")
+
+// NewGroup creates a Plugin for the engine group, and initializes the trace,metric of its members.
+func NewGroup(group *xorm.EngineGroup, opts ...Option) *Plugin {
+	p := newPlugin(opts...)
+	p.registerGroup(group)
+
+	return p
+}
+
+// InitializeGroup initializes the trace,metric of the engine group members with the default Plugin,
+// as Initialize does for an engine. The DBStats metrics and the spans of each member get a db.instance.role
+// attribute, primary or replica. The spans get the peer attributes of the member which served the statement:
+// the replica serving the auto-commit SELECTs of a group session is only known when the members are opened
+// with a RegisterDriver driver name, those spans get neither a db.instance.role nor peer attributes otherwise.
+func InitializeGroup(group *xorm.EngineGroup, opts ...Option) {
+	defaultPlugin(opts...).registerGroup(group)
+}
+
+func (p *Plugin) registerGroup(group *xorm.EngineGroup) {
+	p.register(group.Master(), dbInstanceRole.String(RolePrimary))
+	for _, replica := range group.Slaves() {
+		p.register(replica, dbInstanceRole.String(RoleReplica))
+	}
+
+	p.groups.Store(group.Master().DB().DB, group)
+}
+
+// servedBy returns the pool which served the statement of the session, nil when unknown.
+// xorm runs the auto-commit SELECTs of a group session on a replica picked by the group policy,
+// which the traced connections of the members tell, see RegisterDriver: such a statement is attributed
+// to no member when they are not traced. The other statements are attributed to the session pool,
+// as are the ones of an engine session, e.g. on group.Master().
+func (p *Plugin) servedBy(ctx context.Context, session *xorm.Session, query string) *sql.DB {
+	db := session.DB().DB
+	v, ok := p.groups.Load(db)
+	if !ok {
+		return db
+	}
+
+	if dsn := servedDSN(ctx); dsn != "" {
+		group := v.(*xorm.EngineGroup)
+		var served *sql.DB
+		for _, member := range append([]*xorm.Engine{group.Master()}, group.Slaves()...) {
+			if member.DataSourceName() != dsn {
+				continue
+			}
+			if served != nil && served != member.DB().DB {
+				served = nil // the members share the data source name
+				break
+			}
+			served = member.DB().DB
+		}
+		if served != nil {
+			return served
+		}
+	}
+
+	if mayRunOnReplica(session, query) {
+		return nil
+	}
+	return db
+}
+
+// groupSession is the xorm sessionType of the sessions created by the engine group.
+const groupSession = 1
+
+// mayRunOnReplica tells whether xorm may run the statement of the session on a replica, an auto-commit SELECT
+// of a group session. xorm keeps the session type unexported, an unknown one is taken for a group session.
+func mayRunOnReplica(session *xorm.Session, query string) bool {
+	if dbOperation(query) != "select" {
+		return false
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(session))
+	typ, autoCommit := v.FieldByName("sessionType"), v.FieldByName("isAutoCommit")
+	if typ.Kind() != reflect.Int || autoCommit.Kind() != reflect.Bool {
+		return true
+	}
+	return typ.Int() == groupSession && autoCommit.Bool()
+}
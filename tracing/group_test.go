@@ -0,0 +1,139 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-xorm/xorm"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	xormCore "xorm.io/core"
+)
+
+func TestGroup(t *testing.T) {
+	for _, traced := range []bool{true, false} {
+		traced := traced
+
+		t.Run(fmt.Sprintf("traced=%v", traced), func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+			driverName := xormCore.SQLITE
+			if traced {
+				driverName = tracedSQLite(t)
+			}
+			primary, err := xorm.NewEngine(driverName, "file:primary?mode=memory&cache=shared")
+			require.NoError(t, err)
+			replica, err := xorm.NewEngine(driverName, "file:replica?mode=memory&cache=shared")
+			require.NoError(t, err)
+
+			group, err := xorm.NewEngineGroup(primary, []*xorm.Engine{replica})
+			require.NoError(t, err)
+			defer group.Close()
+
+			opts := []Option{WithTracerProvider(provider), WithoutMetrics()}
+			if traced {
+				opts = append(opts, WithAutoTracing())
+			}
+			p := NewGroup(group, opts...)
+
+			ctx, session := p.BeforeWithSession(context.TODO(), RawAsSpanName, group.NewSession())
+			_, err = session.Query("SELECT 42")
+			p.After(ctx, group.DriverName(), "", -1, session, err)
+			require.NoError(t, err)
+
+			ctx, session = p.BeforeWithSession(context.TODO(), RawAsSpanName, group.NewSession())
+			_, err = session.Exec("CREATE TABLE IF NOT EXISTS group_user (id INTEGER)")
+			p.After(ctx, group.DriverName(), "group_user", -1, session, err)
+			require.NoError(t, err)
+
+			// an engine session runs its SELECTs on the primary.
+			ctx, session = p.Before(context.TODO(), RawAsSpanName, group.Master())
+			_, err = session.Query("SELECT 42")
+			p.After(ctx, group.DriverName(), "", -1, session, err)
+			require.NoError(t, err)
+
+			spans := sr.Ended()
+			var names []string
+			for _, s := range spans {
+				if s.Name() != RawAsSpanName {
+					continue
+				}
+				m := attrMap(s.Attributes())
+				name, hasName := m[semconv.DBNameKey]
+				role, hasRole := m[dbInstanceRole]
+				require.Equal(t, hasName, hasRole)
+				require.Equal(t, name.AsString(), role.AsString())
+				names = append(names, name.AsString())
+			}
+
+			// the replica which served the SELECT is only known through the traced connections,
+			// the span names no member otherwise.
+			if traced {
+				require.Equal(t, []string{"replica", "primary", "primary"}, names)
+			} else {
+				require.Equal(t, []string{"", "primary", "primary"}, names)
+			}
+		})
+	}
+}
+
+func TestGroupReplicas(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	primary, err := xorm.NewEngine(tracedSQLite(t), "file:primary?mode=memory&cache=shared")
+	require.NoError(t, err)
+	var replicas []*xorm.Engine
+	for _, dsn := range []string{"file:replica1?mode=memory&cache=shared", "file:replica2?mode=memory&cache=shared"} {
+		replica, err := xorm.NewEngine(tracedSQLite(t), dsn)
+		require.NoError(t, err)
+		replicas = append(replicas, replica)
+	}
+
+	group, err := xorm.NewEngineGroup(primary, replicas)
+	require.NoError(t, err)
+	defer group.Close()
+
+	p := NewGroup(group, WithTracerProvider(provider), WithoutMetrics(), WithAutoTracing())
+
+	// the group sessions, on the replicas in turn.
+	for i := 0; i < 2; i++ {
+		ctx, session := p.BeforeWithSession(context.TODO(), RawAsSpanName, group.NewSession())
+		_, err = session.Query("SELECT 42")
+		p.After(ctx, group.DriverName(), "", -1, session, err)
+		require.NoError(t, err)
+	}
+
+	// the engine sessions of the primary.
+	ctx, session := p.Before(context.TODO(), RawAsSpanName, group.Master())
+	_, err = session.Query("SELECT 42")
+	p.After(ctx, group.DriverName(), "", -1, session, err)
+	require.NoError(t, err)
+
+	tx, err := p.BeginTx(context.TODO(), primary)
+	require.NoError(t, err)
+	ctx, session = tx.Before(RawAsSpanName)
+	_, err = session.Query("SELECT 42")
+	tx.After(ctx, "", -1, err)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	var names []string
+	for _, s := range sr.Ended() {
+		if s.Name() != RawAsSpanName {
+			continue
+		}
+		m := attrMap(s.Attributes())
+		role := RoleReplica
+		if m[semconv.DBNameKey].AsString() == "primary" {
+			role = RolePrimary
+		}
+		require.Equal(t, role, m[dbInstanceRole].AsString())
+		names = append(names, m[semconv.DBNameKey].AsString())
+	}
+	require.Equal(t, []string{"replica1", "replica2", "primary", "primary"}, names)
+}
@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"errors"
+
+	"github.com/go-xorm/xorm"
+	"go.opentelemetry.io/otel"
+)
+
+var errAutoTracingDriver = errors.New("tracing: WithAutoTracing needs the engine to be opened with a RegisterDriver driver name")
+
+// registerHook checks that every statement executed through the engine gets a span: the engine must be opened
+// with a RegisterDriver driver name, whose connections trace the statements in the trace of the session context,
+// e.g. engine.Context(ctx), with the status of the statement error and the rows affected.
+//
+// xorm v0.7 exposes no context hook and the engine pool is the application's, which is not rebuilt behind it:
+// an engine of another driver is left untraced and the error is reported to otel.Handle.
+func (p *Plugin) registerHook(db *xorm.Engine) {
+	if _, ok := db.DB().DB.Driver().(*tracedDriver); !ok {
+		otel.Handle(errAutoTracingDriver)
+	}
+}
+
+func operationSpanName(operation string) string {
+	switch operation {
+	case "select":
+		return QueryAsSpanName
+	case "insert":
+		return CreatAsSpanName
+	case "update":
+		return UpdateAsSpanName
+	case "delete":
+		return DeleteAsSpanName
+	default:
+		return RawAsSpanName
+	}
+}
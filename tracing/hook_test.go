@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/go-xorm/xorm"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	xormCore "xorm.io/core"
+)
+
+type hookUser struct {
+	ID   int64 `xorm:"pk autoincr 'id'"`
+	Name string
+}
+
+func TestHook(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	name, err := RegisterDriver(registerSQLite(), WithTracerProvider(provider), WithDriverName(xormCore.SQLITE), WithoutMetrics())
+	require.NoError(t, err)
+
+	db, err := xorm.NewEngine(name, "file:hook?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer db.Close()
+
+	p := New(db, WithTracerProvider(provider), WithDriverName(xormCore.SQLITE), WithoutMetrics(), WithAutoTracing())
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+
+	_, err = db.Context(ctx).Exec("CREATE TABLE hook_user (id integer primary key autoincrement, name text)")
+	require.NoError(t, err)
+	_, err = db.Context(ctx).Insert(&hookUser{Name: "foo"})
+	require.NoError(t, err)
+	var u hookUser
+	has, err := db.Context(ctx).Where("name = ?", "foo").Get(&u)
+	require.NoError(t, err)
+	require.True(t, has)
+	_, err = db.Context(ctx).Exec("DELETE FROM hook_user WHERE name = ?", "bar")
+	require.NoError(t, err)
+	_, err = db.Context(ctx).Exec("UPDATE hook_missing SET name = ?", "foo")
+	require.Error(t, err)
+
+	// traced once, by Before/After.
+	sctx, session := p.Before(ctx, RawAsSpanName, db)
+	_, err = session.Exec("SELECT 42")
+	p.After(sctx, db.DriverName(), "", -1, session, err)
+	require.NoError(t, err)
+
+	parent.End()
+
+	var spans []sdktrace.ReadOnlySpan
+	for _, s := range sr.Ended() {
+		if s.Name() != ConnectAsSpanName && s.Name() != "parent" {
+			spans = append(spans, s)
+		}
+	}
+	require.Equal(t, 6, len(spans))
+
+	for i, name := range []string{RawAsSpanName, CreatAsSpanName, QueryAsSpanName, DeleteAsSpanName, UpdateAsSpanName, RawAsSpanName} {
+		s := spans[i]
+		require.Equal(t, name, s.Name())
+		require.Equal(t, trace.SpanKindClient, s.SpanKind())
+		require.Equal(t, parent.SpanContext().TraceID(), s.SpanContext().TraceID())
+
+		sys, ok := attrMap(s.Attributes())[semconv.DBSystemKey]
+		require.True(t, ok)
+		require.Equal(t, xormCore.SQLITE, sys.AsString())
+	}
+
+	m := attrMap(spans[1].Attributes())
+	require.Equal(t, codes.Ok, spans[1].Status().Code)
+	require.Equal(t, "hook_user", m[semconv.DBSQLTableKey].AsString())
+	require.Equal(t, int64(1), m[dbRowsAffected].AsInt64())
+
+	m = attrMap(spans[2].Attributes())
+	require.Equal(t, "SELECT `id`, `name` FROM `hook_user` WHERE (name = 'foo') LIMIT 1", m[semconv.DBStatementKey].AsString())
+	require.Equal(t, int64(1), m[dbRowsReturned].AsInt64())
+
+	require.Equal(t, int64(0), attrMap(spans[3].Attributes())[dbRowsAffected].AsInt64())
+	require.Equal(t, codes.Error, spans[4].Status().Code)
+	require.Equal(t, "SELECT 42", attrMap(spans[5].Attributes())[semconv.DBStatementKey].AsString())
+}
+
+var (
+	errs     = &errorHandler{}
+	errsOnce sync.Once
+)
+
+// errorHandler records the errors handed to otel.Handle.
+type errorHandler struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (h *errorHandler) Handle(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errs = append(h.errs, err)
+}
+
+func (h *errorHandler) handled() []error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]error(nil), h.errs...)
+}
+
+// handleErrors records the errors handed to otel.Handle, the handler is set once for all the tests
+// as the global one cannot be restored.
+func handleErrors() *errorHandler {
+	errsOnce.Do(func() { otel.SetErrorHandler(errs) })
+	errs.mu.Lock()
+	errs.errs = nil
+	errs.mu.Unlock()
+	return errs
+}
+
+func TestHookUntracedDriver(t *testing.T) {
+	errs := handleErrors()
+
+	db, err := xorm.NewEngine(xormCore.SQLITE, "file:hook_untraced?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxIdleConns(7)
+
+	pool := db.DB().DB
+	New(db, WithoutMetrics(), WithAutoTracing())
+
+	// the pool of the application is neither replaced nor closed.
+	require.Same(t, pool, db.DB().DB)
+	require.NoError(t, db.Ping())
+	require.Contains(t, errs.handled(), errAutoTracingDriver)
+}
@@ -1,65 +1,152 @@
 package tracing
 
 import (
+	"time"
+
+	"github.com/dapings/opentelemetry-xorm/logger"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
-type Option func(p *plugin)
+type Option func(p *Plugin)
 
 // WithTracerProvider configures a tracer provider that is used to create a tracer.
 func WithTracerProvider(provider trace.TracerProvider) Option {
-	return func(p *plugin) {
+	return func(p *Plugin) {
 		p.provider = provider
 	}
 }
 
 // WithAttributes configures attributes that are used to create a span.
 func WithAttributes(attrs ...attribute.KeyValue) Option {
-	return func(p *plugin) {
+	return func(p *Plugin) {
 		p.attrs = append(p.attrs, attrs...)
 	}
 }
 
 // WithDriverName configures a db.system attribute.
 func WithDriverName(driverName string) Option {
-	return func(p *plugin) {
+	return func(p *Plugin) {
 		p.attrs = append(p.attrs, semconv.DBSystemKey.String(driverName))
 	}
 }
 
 // WithDBName configures a db.name attribute.
 func WithDBName(name string) Option {
-	return func(p *plugin) {
+	return func(p *Plugin) {
 		p.attrs = append(p.attrs, semconv.DBNameKey.String(name))
 	}
 }
 
 // WithTableName configures a db.sql.table attribute.
 func WithTableName(name string) Option {
-	return func(p *plugin) {
+	return func(p *Plugin) {
 		p.attrs = append(p.attrs, semconv.DBSQLTableKey.String(name))
 	}
 }
 
 // WithoutQueryVariables configures the db.statement attribute to exclude query variables
 func WithoutQueryVariables() Option {
-	return func(p *plugin) {
+	return func(p *Plugin) {
 		p.excludeQueryVars = true
 	}
 }
 
+// WithRedactionPolicy configures the query variables which are redacted from the db.statement attribute.
+func WithRedactionPolicy(policy *logger.RedactionPolicy) Option {
+	return func(p *Plugin) {
+		p.redactionPolicy = policy
+	}
+}
+
+// WithStatementSanitizer configures a sanitizer of the db.statement attribute, e.g. logger.ObfuscateSQL,
+// or logger.DialectOf("mysql").ObfuscateSQL which reads the string literals as the database does.
+// The query variables are not inlined into a sanitized statement.
+func WithStatementSanitizer(sanitizer func(query string) string) Option {
+	return func(p *Plugin) {
+		p.sanitizer = sanitizer
+	}
+}
+
 // WithQueryFormatter configures a query formatter
 func WithQueryFormatter(queryFormatter func(query string) string) Option {
-	return func(p *plugin) {
+	return func(p *Plugin) {
 		p.queryFormatter = queryFormatter
 	}
 }
 
-// WithoutMetrics prevents DBStats metrics from being reported.
+// WithSpanNameFormatter configures a span name formatter, e.g. SemConvSpanName.
+func WithSpanNameFormatter(formatter SpanNameFormatter) Option {
+	return func(p *Plugin) {
+		p.spanNameFormatter = formatter
+	}
+}
+
+// WithSlowQueryThreshold marks the statements lasting at least d as slow queries:
+// a db.slow_query attribute, a span event with the explained statement and a slow query count.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(p *Plugin) {
+		p.slowThreshold = d
+	}
+}
+
+// WithExplain runs the EXPLAIN of the SELECT statements lasting at least cfg.Threshold on the same engine
+// in the background, the plan is added as an event of an ExplainAsSpanName child span.
+// The EXPLAIN are sampled and rate-limited, see ExplainConfig. As the plans may quote the query variables,
+// there is no EXPLAIN with a sanitizer, WithoutQueryVariables or a redaction policy, nor in a Tx.
+func WithExplain(cfg ExplainConfig) Option {
+	return func(p *Plugin) {
+		p.explainer = newExplainer(cfg)
+	}
+}
+
+// WithErrorClassifier configures the classification of the statement errors, ClassifyError by default.
+// The expected errors, e.g. a unique violation of an upsert, are not recorded as span errors.
+func WithErrorClassifier(classifier ErrorClassifier) Option {
+	return func(p *Plugin) {
+		p.errorClassifier = classifier
+	}
+}
+
+// WithoutCanceledErrors does not record the statements failing with a canceled context as span errors,
+// e.g. the queries of a client closing its connection. The exceeded deadlines are still errors.
+func WithoutCanceledErrors() Option {
+	return func(p *Plugin) {
+		p.canceledNotError = true
+	}
+}
+
+// WithMeterProvider configures a meter provider that is used to create the operation metrics.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(p *Plugin) {
+		p.meterProvider = provider
+	}
+}
+
+// WithLegacyPoolMetricNames reports the DBStats metrics with the go.sql.* names of the previous releases
+// instead of the db.client.connections.* semantic convention ones.
+func WithLegacyPoolMetricNames() Option {
+	return func(p *Plugin) {
+		p.legacyPoolMetrics = true
+	}
+}
+
+// WithoutMetrics prevents DBStats and operation metrics from being reported.
 func WithoutMetrics() Option {
-	return func(p *plugin) {
+	return func(p *Plugin) {
 		p.excludeMetrics = true
 	}
 }
+
+// WithAutoTracing traces every statement executed through the engine passed to Initialize or New without
+// calling Before/After. The engine must be opened with a RegisterDriver driver name, whose connections trace
+// the statements, the engine pool is left untouched: another driver is reported to otel.Handle.
+// The spans are children of the session context, e.g. engine.Context(ctx), the statements traced by Before/After
+// get no additional span.
+func WithAutoTracing() Option {
+	return func(p *Plugin) {
+		p.autoTracing = true
+	}
+}
@@ -3,11 +3,13 @@ package tracing
 import (
 	"context"
 	"database/sql"
-	"database/sql/driver"
-	"io"
+	"errors"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/dapings/opentelemetry-xorm/logger"
 	"github.com/go-xorm/xorm"
@@ -17,6 +19,7 @@ import (
 	"github.com/dapings/opentelemetry-xorm/metrics"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 	xormCore "xorm.io/core"
 )
@@ -30,6 +33,8 @@ const (
 	UpdateAsSpanName = "xorm:update" // Update
 	RowAsSpanName    = "xorm:row"    // Rows or Iterate
 	RawAsSpanName    = "xorm:raw"    // the raw SQL execution: Query or Exec a SQL string
+
+	slowQueryEventName = "slow query"
 )
 
 var (
@@ -37,24 +42,56 @@ var (
 	cCommentRegex    = regexp.MustCompile(`(?is)/\*.*?\*/`)
 	lineCommentRegex = regexp.MustCompile(`(?im)(?:--|#).*?$`)
 	sqlPrefixRegex   = regexp.MustCompile(`^[\s;]*`)
+	sqlTableRegex    = regexp.MustCompile("(?is)\\b(?:from|into|update|table)\\s+(?:if\\s+(?:not\\s+)?exists\\s+)?([`\"\\[]?[\\w.]+[`\"\\]]?)")
 
 	dbRowsAffected = attribute.Key("db.rows_affected")
+	errorType      = attribute.Key("error.type")
+
+	dbDeadlineRemaining = attribute.Key("db.context.deadline_remaining")
+
+	dbSlowQuery     = attribute.Key("db.slow_query")
+	dbQueryDuration = attribute.Key("db.query.duration")
 
-	defaultXORMPlugin *plugin
+	defaultXORMPlugin *Plugin
 	defaultPluginOnce sync.Once
+
+	errDefaultPluginOptions = errors.New("tracing: the options of the default Plugin are the ones of the first Initialize, use New for an engine with its own options")
 )
 
-type plugin struct {
-	provider         trace.TracerProvider
-	tracer           trace.Tracer
-	attrs            []attribute.KeyValue
-	excludeQueryVars bool
-	excludeMetrics   bool
-	queryFormatter   func(query string) string
+// Plugin traces the xorm actions of an engine, each engine may have its own Plugin.
+type Plugin struct {
+	provider          trace.TracerProvider
+	tracer            trace.Tracer
+	attrs             []attribute.KeyValue
+	excludeQueryVars  bool
+	excludeMetrics    bool
+	legacyPoolMetrics bool
+	meterProvider     metric.MeterProvider
+	metrics           *metrics.OperationMetrics
+	autoTracing       bool
+	queryFormatter    func(query string) string
+	sanitizer         func(query string) string
+	redactionPolicy   *logger.RedactionPolicy
+	spanNameFormatter SpanNameFormatter
+	slowThreshold     time.Duration
+	explainer         *explainer
+	explainNext       *atomic.Int64   // the rate limit of the explainer, see explainer.allow
+	explains          *sync.WaitGroup // the running EXPLAIN
+	errorClassifier   ErrorClassifier
+	canceledNotError  bool
+	peers             *sync.Map // *sql.DB of the registered engines to their DSN attributes
+	groups            *sync.Map // *sql.DB of the registered group primaries to their *xorm.EngineGroup
+	pools             *sync.Map // *sql.DB of the registered engines to their *pool
+}
+
+// pool is the DBStats metrics registration of an engine.
+type pool struct {
+	name string
+	reg  *metrics.Registration
 }
 
-func newPlugin(opts ...Option) *plugin {
-	p := &plugin{}
+func newPlugin(opts ...Option) *Plugin {
+	p := &Plugin{peers: &sync.Map{}, groups: &sync.Map{}, pools: &sync.Map{}, explainNext: &atomic.Int64{}, explains: &sync.WaitGroup{}}
 	for _, opt := range opts {
 		opt(p)
 	}
@@ -65,43 +102,195 @@ func newPlugin(opts ...Option) *plugin {
 
 	p.tracer = p.provider.Tracer("xorm.io/opentelemetry")
 
+	if !p.excludeMetrics {
+		if p.meterProvider == nil {
+			p.meterProvider = otel.GetMeterProvider()
+		}
+
+		m, err := metrics.NewOperationMetrics(p.meterProvider)
+		if err != nil {
+			otel.Handle(err)
+		} else {
+			p.metrics = m
+		}
+	}
+
 	return p
 }
 
-// Initialize initializes the trace,metric.
+// New creates a Plugin for the engine, and initializes its trace,metric.
+func New(db *xorm.Engine, opts ...Option) *Plugin {
+	p := newPlugin(opts...)
+	p.register(db)
+
+	return p
+}
+
+// Initialize initializes the trace,metric of the default Plugin used by the package-level functions.
+// The spans of the engine statements get the server.address, server.port, db.user, db.name and db.connection_string
+// attributes of its data source name, the password stripped.
+// The options of the first call are kept, the ones of the later calls are reported to otel.Handle:
+// use New for engines which need their own options.
 func Initialize(db *xorm.Engine, opts ...Option) {
+	defaultPlugin(opts...).register(db)
+}
+
+// defaultPlugin returns the default Plugin, created with the opts of the first call.
+func defaultPlugin(opts ...Option) *Plugin {
+	created := false
 	defaultPluginOnce.Do(func() {
 		defaultXORMPlugin = newPlugin(opts...)
+		created = true
 	})
+	if !created && len(opts) > 0 {
+		otel.Handle(errDefaultPluginOptions)
+	}
 
-	p := defaultXORMPlugin
-	if !p.excludeMetrics {
-		metrics.ReportDBStatsMetrics(db.DB().DB)
+	return defaultXORMPlugin
+}
+
+// register initializes the trace,metric of the engine, the attrs are added to its spans and DBStats metrics.
+func (p *Plugin) register(db *xorm.Engine, attrs ...attribute.KeyValue) {
+	if p.autoTracing {
+		p.registerHook(db)
+	}
+
+	peer := append(dsnAttributes(db.DriverName(), db.DataSourceName()), attrs...)
+	if sys := dbSystem(db.DriverName()); sys.Valid() {
+		peer = append(peer, sys)
+	}
+	p.peers.Store(db.DB().DB, peer)
+
+	if _, ok := p.pools.Load(db.DB().DB); !ok && !p.excludeMetrics {
+		opts := []metrics.Option{metrics.WithMeterProvider(p.meterProvider), metrics.WithAttributes(attrs...)}
+		if p.legacyPoolMetrics {
+			opts = append(opts, metrics.WithLegacyNames())
+		}
+		if sys := dbSystem(db.DriverName()); sys.Valid() {
+			opts = append(opts, metrics.WithDBSystem(sys.Value.AsString()))
+		}
+		name := attrValue(p.attrs, semconv.DBNameKey)
+		if !name.Valid() {
+			name = attrValue(peer, semconv.DBNameKey)
+		}
+		if name.Valid() {
+			opts = append(opts, metrics.WithDBName(name.Value.AsString()))
+		}
+		poolName := reservePoolName(peer, name, db.DriverName())
+		opts = append(opts, metrics.WithPoolName(poolName))
+
+		if reg, err := metrics.RegisterDBStatsMetrics(db.DB().DB, opts...); err != nil {
+			releasePoolName(poolName)
+			otel.Handle(err)
+		} else {
+			p.pools.Store(db.DB().DB, &pool{name: poolName, reg: reg})
+		}
 	}
 }
 
-// Before uses the ctx,spanName,engine to start tracer, creates session.
+var (
+	poolNamesMu sync.Mutex
+	poolNames   = make(map[string]bool)
+)
+
+// reservePoolName returns the unique pool.name of an engine, server.address:server.port/db.name
+// or the driver name, suffixed with a sequence number when another registered engine has the same.
+func reservePoolName(peer []attribute.KeyValue, dbName attribute.KeyValue, driverName string) string {
+	name := driverName
+	if host := attrValue(peer, serverAddress); host.Valid() {
+		name = host.Value.AsString()
+		if port := attrValue(peer, serverPort); port.Valid() {
+			name += ":" + port.Value.Emit()
+		}
+	}
+	if dbName.Valid() {
+		name += "/" + dbName.Value.AsString()
+	}
+
+	poolNamesMu.Lock()
+	defer poolNamesMu.Unlock()
+
+	unique := name
+	for i := 2; poolNames[unique]; i++ {
+		unique = name + "#" + strconv.Itoa(i)
+	}
+	poolNames[unique] = true
+	return unique
+}
+
+func releasePoolName(name string) {
+	poolNamesMu.Lock()
+	delete(poolNames, name)
+	poolNamesMu.Unlock()
+}
+
+// Close stops reporting the DBStats metrics of the engines registered by the default Plugin.
+func Close() error {
+	if defaultXORMPlugin == nil {
+		return nil
+	}
+	return defaultXORMPlugin.Close()
+}
+
+// Close stops reporting the DBStats metrics of the engines registered by the plugin, and waits for the running EXPLAIN.
+// The statements of the engines are still traced, it returns the first unregistration error.
+func (p *Plugin) Close() error {
+	var err error
+	p.pools.Range(func(db, v any) bool {
+		p.pools.Delete(db)
+		pool := v.(*pool)
+		releasePoolName(pool.name)
+		if e := pool.reg.Unregister(); e != nil && err == nil {
+			err = e
+		}
+		return true
+	})
+	p.explains.Wait()
+
+	return err
+}
+
+// Before uses the ctx,spanName,engine to start tracer of the default Plugin, creates session.
 func Before(ctx context.Context, spanName string, tx *xorm.Engine) (context.Context, *xorm.Session) {
-	p := *defaultXORMPlugin
+	return defaultXORMPlugin.Before(ctx, spanName, tx)
+}
 
+// BeforeWithSession uses the ctx,spanName,session to start tracer of the default Plugin, creates session.
+func BeforeWithSession(ctx context.Context, spanName string, session *xorm.Session) (context.Context, *xorm.Session) {
+	return defaultXORMPlugin.BeforeWithSession(ctx, spanName, session)
+}
+
+// After collects the trace data of the default Plugin after session actions.
+func After(ctx context.Context, driverName, tableName string, rowsAffected int64, tx *xorm.Session, txErr error, opts ...Option) {
+	defaultXORMPlugin.After(ctx, driverName, tableName, rowsAffected, tx, txErr, opts...)
+}
+
+// Before uses the ctx,spanName,engine to start tracer, creates session.
+func (p *Plugin) Before(ctx context.Context, spanName string, tx *xorm.Engine) (context.Context, *xorm.Session) {
 	return p.before(ctx, spanName, tx, nil)
 }
 
 // BeforeWithSession uses the ctx,spanName,session to start tracer, creates session.
-func BeforeWithSession(ctx context.Context, spanName string, session *xorm.Session) (context.Context, *xorm.Session) {
-	p := *defaultXORMPlugin
-
+func (p *Plugin) BeforeWithSession(ctx context.Context, spanName string, session *xorm.Session) (context.Context, *xorm.Session) {
 	return p.before(ctx, spanName, nil, session)
 }
 
-func (p *plugin) before(ctx context.Context, spanName string, tx *xorm.Engine, session *xorm.Session) (context.Context, *xorm.Session) {
+func (p *Plugin) before(ctx context.Context, spanName string, tx *xorm.Engine, session *xorm.Session) (context.Context, *xorm.Session) {
 	if ctx == nil {
 		// Prevent trace.ContextWithSpan from panicking.
 		ctx = context.Background()
 	}
 
+	var startAttr trace.SpanStartOption
+	if session != nil {
+		startAttr = p.startAttributes("", session.DB().DB)
+	} else {
+		startAttr = p.startAttributes(tx.DriverName(), nil)
+	}
+
 	// default trace.ContextWithSpan(ctx, span)
-	ctx, _ = p.tracer.Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindClient))
+	ctx = withStartTime(ctx)
+	ctx, _ = p.tracer.Start(withAcquisition(ctx, startTime(ctx)), spanName, trace.WithSpanKind(trace.SpanKindClient), startAttr)
 
 	if session != nil {
 		session = session.Context(ctx).Clone() // a new session, use ctx
@@ -113,67 +302,274 @@ func (p *plugin) before(ctx context.Context, spanName string, tx *xorm.Engine, s
 }
 
 // After collects the trace data after session actions.
-func After(ctx context.Context, driverName, tableName string, rowsAffected int64, tx *xorm.Session, txErr error, opts ...Option) {
-	p := *defaultXORMPlugin
-
+func (p *Plugin) After(ctx context.Context, driverName, tableName string, rowsAffected int64, tx *xorm.Session, txErr error, opts ...Option) {
 	p.after(ctx, driverName, tableName, rowsAffected, tx, txErr, opts...)
-	return
 }
 
-func (p *plugin) after(ctx context.Context, driverName, tableName string, rowsAffected int64, tx *xorm.Session, txErr error, opts ...Option) {
+func (p *Plugin) after(ctx context.Context, driverName, tableName string, rowsAffected int64, tx *xorm.Session, txErr error, opts ...Option) {
 	span := trace.SpanFromContext(ctx)
-	if !span.IsRecording() {
+	if !span.IsRecording() && p.metrics == nil {
 		return
 	}
 
 	defer span.End()
 
+	if len(opts) > 0 {
+		p = p.with(opts...)
+	}
+
+	stmt := statement{
+		driverName:   driverName,
+		tableName:    tableName,
+		rowsAffected: rowsAffected,
+		start:        startTime(ctx),
+		err:          txErr,
+	}
+	if tx != nil {
+		stmt.query, stmt.vars = tx.LastSQL()
+		stmt.db = p.servedBy(ctx, tx, stmt.query)
+		stmt.wait, stmt.waitKnown = waitTime(ctx)
+	}
+
+	p.end(ctx, span, stmt)
+}
+
+type startTimeKey struct{}
+
+// withStartTime stores the start time of the action, After uses it for the duration metric.
+func withStartTime(ctx context.Context) context.Context {
+	return context.WithValue(ctx, startTimeKey{}, time.Now())
+}
+
+func startTime(ctx context.Context) time.Time {
+	t, _ := ctx.Value(startTimeKey{}).(time.Time)
+	return t
+}
+
+// inStatement tells whether ctx is the one of a statement traced by Before/After, which the driver does not trace again.
+func inStatement(ctx context.Context) bool {
+	return !startTime(ctx).IsZero()
+}
+
+// with returns a call-scoped copy of the plugin with opts applied, p itself is left untouched.
+func (p *Plugin) with(opts ...Option) *Plugin {
+	c := *p
+	c.attrs = make([]attribute.KeyValue, len(p.attrs), len(p.attrs)+len(opts))
+	copy(c.attrs, p.attrs)
+
 	for _, opt := range opts {
-		opt(p)
+		opt(&c)
 	}
 
-	attrs := make([]attribute.KeyValue, 0, len(p.attrs)+4)
+	return &c
+}
+
+// statement is the data collected about a traced db action.
+type statement struct {
+	driverName   string
+	tableName    string
+	rowsAffected int64 // -1 when unknown
+	query        string
+	vars         []any
+	start        time.Time // zero when unknown
+	err          error
+	db           *sql.DB       // the pool which served the statement, nil when unknown
+	wait         time.Duration // the wait for a pool connection, see waitTime
+	waitKnown    bool
+	prepare      bool // the prepare of the query, which runs no operation
+}
+
+// operation tells whether the statement runs an operation, which the operation and slow query metrics count:
+// the connect, prepare and transaction spans do not.
+func (stmt statement) operation() bool {
+	return stmt.query != "" && !stmt.prepare
+}
+
+// end sets the db attributes and the status on the span and records the operation metrics,
+// the caller is responsible for ending the span.
+func (p *Plugin) end(ctx context.Context, span trace.Span, stmt statement) {
+	duration := time.Duration(-1)
+	if !stmt.start.IsZero() {
+		duration = time.Since(stmt.start)
+	}
+	slow := stmt.operation() && p.slowThreshold > 0 && duration >= p.slowThreshold
+
+	attrs := make([]attribute.KeyValue, 0, len(p.attrs)+8)
+	if stmt.db != nil {
+		if peer, ok := p.peers.Load(stmt.db); ok {
+			attrs = append(attrs, peer.([]attribute.KeyValue)...) // the options take precedence
+		}
+	}
 	attrs = append(attrs, p.attrs...)
 
-	if sys := dbSystem(driverName); sys.Valid() {
+	sys := dbSystem(stmt.driverName)
+	if sys.Valid() {
 		attrs = append(attrs, sys)
+	} else {
+		sys = attrValue(p.attrs, semconv.DBSystemKey)
 	}
 
-	if tx != nil {
-		query, vars := tx.LastSQL()
-		if !p.excludeQueryVars {
-			query = logger.ExplainSQL(query, nil, `'`, vars...)
+	var formatQuery, operation string
+	if query := stmt.query; query != "" {
+		if p.sanitizer != nil {
+			query = p.sanitizer(query)
+		} else if !p.excludeQueryVars {
+			query = logger.DialectOf(sys.Value.AsString()).ExplainSQL(query, p.redactionPolicy, stmt.vars...)
 		}
 
-		formatQuery := p.formatQuery(query)
+		formatQuery = p.formatQuery(query)
+		operation = dbOperation(formatQuery)
 		attrs = append(attrs, semconv.DBStatementKey.String(formatQuery))
-		attrs = append(attrs, semconv.DBOperationKey.String(dbOperation(formatQuery)))
+		attrs = append(attrs, semconv.DBOperationKey.String(operation))
 	}
 
-	if tableName != "" {
-		attrs = append(attrs, semconv.DBSQLTableKey.String(tableName))
+	if stmt.tableName != "" {
+		attrs = append(attrs, semconv.DBSQLTableKey.String(stmt.tableName))
 	}
-	if rowsAffected != -1 {
-		attrs = append(attrs, dbRowsAffected.Int64(rowsAffected))
+	if stmt.rowsAffected != -1 {
+		attrs = append(attrs, dbRowsAffected.Int64(stmt.rowsAffected))
+	}
+	if slow {
+		attrs = append(attrs, dbSlowQuery.Bool(true))
+	}
+	if stmt.waitKnown {
+		attrs = append(attrs, dbConnWaitTime.Float64(stmt.wait.Seconds()))
+	}
+	if deadline, ok := ctx.Deadline(); ok && !stmt.start.IsZero() {
+		attrs = append(attrs, dbDeadlineRemaining.Float64(deadline.Sub(stmt.start).Seconds()))
 	}
 
 	span.SetAttributes(attrs...)
 
-	switch txErr {
-	case nil,
-		xorm.ErrNotExist,
-		driver.ErrSkip,
-		io.EOF, // end of rows iterator
-		sql.ErrNoRows:
-		// ignore
+	if slow {
+		span.AddEvent(slowQueryEventName, trace.WithAttributes(
+			semconv.DBStatementKey.String(p.explainQuery(sys, stmt)),
+			dbQueryDuration.Float64(duration.Seconds()),
+		))
+	}
+
+	if span.IsRecording() && p.explainable(ctx, stmt) && p.explainer.allow(duration, p.explainNext) {
+		p.explain(span.SpanContext(), stmt.db, sys, stmt)
+	}
+
+	if p.spanNameFormatter != nil {
+		if name := p.spanNameFormatter(operation, stmt.tableName, formatQuery); name != "" {
+			span.SetName(name)
+		}
+	}
+
+	class := p.classify(ctx, stmt.err)
+	if stmt.err != nil && !class.Expected {
+		if class.StatusCode != "" {
+			span.SetAttributes(dbResponseStatusCode.String(class.StatusCode))
+		}
+		span.SetAttributes(errorType.String(class.Type))
+		span.RecordError(stmt.err)
+		span.SetStatus(codes.Error, stmt.err.Error())
+	} else {
 		span.SetStatus(codes.Ok, "")
-	default:
-		span.RecordError(txErr)
-		span.SetStatus(codes.Error, txErr.Error())
+	}
+
+	if v, ok := p.pools.Load(stmt.db); ok {
+		if stmt.waitKnown {
+			v.(*pool).reg.RecordWaitTime(ctx, stmt.wait)
+		} else if acquisitionTimedOut(ctx, stmt.db, stmt.err) {
+			v.(*pool).reg.RecordTimeout(context.Background()) // the SDK drops the measurements of an expired ctx
+		}
+	}
+
+	if p.metrics != nil && stmt.operation() {
+		mAttrs := metricAttrs(attrs, stmt.err, class)
+		p.metrics.Record(ctx, duration, mAttrs...)
+		if slow {
+			p.metrics.RecordSlow(ctx, mAttrs...)
+		}
+	}
+}
+
+// explainQuery returns the statement with its variables, the sanitizer, WithoutQueryVariables
+// and the redaction policy still apply.
+func (p *Plugin) explainQuery(sys attribute.KeyValue, stmt statement) string {
+	if p.sanitizer != nil {
+		return p.sanitizer(stmt.query)
+	}
+	if p.excludeQueryVars {
+		return stmt.query
+	}
+	return logger.DialectOf(sys.Value.AsString()).ExplainSQL(stmt.query, p.redactionPolicy, stmt.vars...)
+}
+
+// startAttributes returns the db.system attribute of the span start, so that the samplers tell the db spans apart.
+// The system of the driver name, else the one of the registered engine pool, else the one of the options.
+func (p *Plugin) startAttributes(driverName string, db *sql.DB) trace.SpanStartOption {
+	sys := dbSystem(driverName)
+	if !sys.Valid() && db != nil {
+		if peer, ok := p.peers.Load(db); ok {
+			sys = attrValue(peer.([]attribute.KeyValue), semconv.DBSystemKey)
+		}
+	}
+	if !sys.Valid() {
+		sys = attrValue(p.attrs, semconv.DBSystemKey)
+	}
+	if !sys.Valid() {
+		return trace.WithAttributes()
+	}
+	return trace.WithAttributes(sys)
+}
+
+// attrValue returns the last attribute of the key, or an invalid one.
+func attrValue(attrs []attribute.KeyValue, key attribute.Key) attribute.KeyValue {
+	for i := len(attrs) - 1; i >= 0; i-- {
+		if attrs[i].Key == key {
+			return attrs[i]
+		}
+	}
+	return attribute.KeyValue{}
+}
+
+// metricAttrs keeps the low cardinality attributes of the span for the operation metrics.
+func metricAttrs(attrs []attribute.KeyValue, err error, class ErrorClass) []attribute.KeyValue {
+	m := make([]attribute.KeyValue, 0, 5)
+	for _, kv := range attrs {
+		switch kv.Key {
+		case semconv.DBSystemKey, semconv.DBNameKey, semconv.DBOperationKey, semconv.DBSQLTableKey:
+			m = append(m, kv)
+		}
+	}
+	if err != nil && !class.Expected {
+		m = append(m, errorType.String(class.Type))
+	}
+	return m
+}
+
+// SpanNameFormatter names a span once its statement is known, an empty name keeps the span name.
+// The operation is the lower case first word of the statement, e.g. select.
+type SpanNameFormatter func(operation, table, query string) string
+
+// SemConvSpanName returns a SpanNameFormatter following the OpenTelemetry database semantic convention,
+// `<db.operation> <db.name>.<db.sql.table>`, e.g. `SELECT shop.orders`.
+func SemConvSpanName(dbName string) SpanNameFormatter {
+	return func(operation, table, query string) string {
+		target := dbName
+		if table != "" {
+			if target != "" {
+				target += "."
+			}
+			target += table
+		}
+
+		switch {
+		case operation == "":
+			return target
+		case target == "":
+			return strings.ToUpper(operation)
+		default:
+			return strings.ToUpper(operation) + " " + target
+		}
 	}
 }
 
-func (p *plugin) formatQuery(query string) string {
+func (p *Plugin) formatQuery(query string) string {
 	if p.queryFormatter != nil {
 		return p.queryFormatter(query)
 	}
@@ -182,13 +578,15 @@ func (p *plugin) formatQuery(query string) string {
 
 func dbSystem(driverName string) attribute.KeyValue {
 	// driverName xorm.Engine.Dialect().DriverName()
-	switch driverName {
+	switch tracedDriverName(driverName) {
 	case xormCore.MYSQL:
 		return semconv.DBSystemMySQL
 	case "odbc", xormCore.MSSQL:
 		return semconv.DBSystemMSSQL
 	case "pgx", xormCore.POSTGRES:
 		return semconv.DBSystemPostgreSQL
+	case "oci8", "goracle", "godror", xormCore.ORACLE:
+		return semconv.DBSystemOracle
 	case xormCore.SQLITE:
 		return semconv.DBSystemKey.String("sqlite3")
 	case "spanner":
@@ -204,3 +602,13 @@ func dbOperation(query string) string {
 	s = sqlPrefixRegex.ReplaceAllString(s, "")
 	return strings.ToLower(firstWordRegex.FindString(s))
 }
+
+func dbTable(query string) string {
+	s := cCommentRegex.ReplaceAllString(query, "")
+	s = lineCommentRegex.ReplaceAllString(s, "")
+	m := sqlTableRegex.FindStringSubmatch(s)
+	if len(m) < 2 {
+		return ""
+	}
+	return strings.Trim(m[1], "`\"[]")
+}
@@ -2,13 +2,22 @@ package tracing
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/dapings/opentelemetry-xorm/logger"
 	"github.com/go-xorm/xorm"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
@@ -18,7 +27,7 @@ import (
 
 type Test struct {
 	name    string
-	do      []func(ctx context.Context, db *xorm.Session, p *plugin)
+	do      []func(ctx context.Context, db *xorm.Session, p *Plugin)
 	require func(t *testing.T, spans []sdktrace.ReadOnlySpan)
 	opts    []Option
 }
@@ -27,8 +36,8 @@ func TestOTEL(t *testing.T) {
 	tests := []Test{
 		{
 			name: "directly select 42",
-			do: []func(ctx context.Context, db *xorm.Session, p *plugin){
-				func(ctx context.Context, db *xorm.Session, p *plugin) {
+			do: []func(ctx context.Context, db *xorm.Session, p *Plugin){
+				func(ctx context.Context, db *xorm.Session, p *Plugin) {
 					session := db.Context(ctx)
 					ctx, session = p.before(ctx, RawAsSpanName, nil, session)
 					_, err := session.Exec("SELECT 42")
@@ -59,8 +68,8 @@ func TestOTEL(t *testing.T) {
 		},
 		{
 			name: "directly select foo_bar",
-			do: []func(ctx context.Context, db *xorm.Session, p *plugin){
-				func(ctx context.Context, db *xorm.Session, p *plugin) {
+			do: []func(ctx context.Context, db *xorm.Session, p *Plugin){
+				func(ctx context.Context, db *xorm.Session, p *Plugin) {
 					session := db.Context(ctx)
 					ctx, session = p.before(ctx, RawAsSpanName, nil, session)
 					var err error
@@ -95,15 +104,15 @@ func TestOTEL(t *testing.T) {
 		},
 		{
 			name: "table foo create and get",
-			do: []func(ctx context.Context, db *xorm.Session, p *plugin){
-				func(ctx context.Context, db *xorm.Session, p *plugin) {
+			do: []func(ctx context.Context, db *xorm.Session, p *Plugin){
+				func(ctx context.Context, db *xorm.Session, p *Plugin) {
 					session := db.Context(ctx)
 					ctx, session = p.before(ctx, RawAsSpanName, nil, session)
 					_, err := session.Exec("CREATE TABLE foo (id int)")
 					p.after(ctx, "", "foo", -1, session, err)
 					require.NoError(t, err)
 				},
-				func(ctx context.Context, db *xorm.Session, p *plugin) {
+				func(ctx context.Context, db *xorm.Session, p *Plugin) {
 					var id int
 					param := 42
 					session := db.Context(ctx)
@@ -137,6 +146,67 @@ func TestOTEL(t *testing.T) {
 			},
 			opts: []Option{WithoutQueryVariables()},
 		},
+		{
+			name: "semconv span name",
+			do: []func(ctx context.Context, db *xorm.Session, p *Plugin){
+				func(ctx context.Context, db *xorm.Session, p *Plugin) {
+					session := db.Context(ctx)
+					ctx, session = p.before(ctx, RawAsSpanName, nil, session)
+					_, err := session.Exec("SELECT 42")
+					p.after(ctx, "", "answers", -1, session, err)
+					require.NoError(t, err)
+				},
+			},
+			require: func(t *testing.T, spans []sdktrace.ReadOnlySpan) {
+				require.Equal(t, 1, len(spans))
+				require.Equal(t, "SELECT shop.answers", spans[0].Name())
+			},
+			opts: []Option{WithSpanNameFormatter(SemConvSpanName("shop"))},
+		},
+		{
+			name: "sanitized statement",
+			do: []func(ctx context.Context, db *xorm.Session, p *Plugin){
+				func(ctx context.Context, db *xorm.Session, p *Plugin) {
+					session := db.Context(ctx)
+					ctx, session = p.before(ctx, RawAsSpanName, nil, session)
+					_, err := session.Query("SELECT 'w@g.com' AS email, ? AS token WHERE 1 IN (1, 2, 3)", "secret")
+					p.after(ctx, "", "", -1, session, err)
+					require.NoError(t, err)
+				},
+			},
+			require: func(t *testing.T, spans []sdktrace.ReadOnlySpan) {
+				require.Equal(t, 1, len(spans))
+
+				m := attrMap(spans[0].Attributes())
+
+				stmt, ok := m[semconv.DBStatementKey]
+				require.True(t, ok)
+				require.Equal(t, "SELECT ? AS email, ? AS token WHERE ? IN (?)", stmt.AsString())
+			},
+			opts: []Option{WithStatementSanitizer(logger.ObfuscateSQL)},
+		},
+		{
+			name: "redacted statement",
+			do: []func(ctx context.Context, db *xorm.Session, p *Plugin){
+				func(ctx context.Context, db *xorm.Session, p *Plugin) {
+					session := db.Context(ctx)
+					ctx, session = p.before(ctx, RawAsSpanName, nil, session)
+					_, err := session.Query("SELECT ? AS email, ? AS name, ? AS token", "w@g.com", "foo", logger.Secret("bar"))
+					p.after(ctx, "", "", -1, session, err)
+					require.NoError(t, err)
+				},
+			},
+			require: func(t *testing.T, spans []sdktrace.ReadOnlySpan) {
+				require.Equal(t, 1, len(spans))
+
+				m := attrMap(spans[0].Attributes())
+
+				stmt, ok := m[semconv.DBStatementKey]
+				require.True(t, ok)
+				require.Equal(t, "SELECT '<redacted>' AS email, 'foo' AS name, '<redacted>' AS token", stmt.AsString())
+			},
+			opts: []Option{WithRedactionPolicy(&logger.RedactionPolicy{Positions: []int{0}})},
+		},
 	}
 
 	for i, test := range tests {
@@ -171,3 +241,553 @@ func attrMap(attrs []attribute.KeyValue) map[attribute.Key]attribute.Value {
 	}
 	return m
 }
+
+func TestPlugins(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	primary, err := xorm.NewEngine(xormCore.SQLITE, "file:primary?mode=memory&cache=shared")
+	require.NoError(t, err)
+	replica, err := xorm.NewEngine(xormCore.SQLITE, "file:replica?mode=memory&cache=shared")
+	require.NoError(t, err)
+
+	plugins := map[string]*Plugin{
+		"primary": New(primary, WithTracerProvider(provider), WithoutMetrics(), WithDBName("primary")),
+		"replica": New(replica, WithTracerProvider(provider), WithoutMetrics(), WithDBName("replica")),
+	}
+	engines := map[string]*xorm.Engine{"primary": primary, "replica": replica}
+
+	for name, p := range plugins {
+		ctx, session := p.Before(context.TODO(), RawAsSpanName, engines[name])
+		_, err := session.Exec("SELECT 42")
+		p.After(ctx, engines[name].DriverName(), "", -1, session, err)
+		require.NoError(t, err)
+	}
+
+	spans := sr.Ended()
+	require.Equal(t, 2, len(spans))
+
+	for _, s := range spans {
+		m := attrMap(s.Attributes())
+
+		sys, ok := m[semconv.DBSystemKey]
+		require.True(t, ok)
+		require.Equal(t, xormCore.SQLITE, sys.AsString())
+
+		dbName, ok := m[semconv.DBNameKey]
+		require.True(t, ok)
+		delete(plugins, dbName.AsString())
+	}
+	require.Empty(t, plugins)
+}
+
+func TestAfterConcurrent(t *testing.T) {
+	const calls = 500
+
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	db, err := xorm.NewEngine(xormCore.SQLITE, "file::memory:?cache=shared")
+	require.NoError(t, err)
+
+	// the appends leave spare capacity in the shared attrs, a leaking per-call append would be visible to the other calls.
+	p := newPlugin(WithTracerProvider(provider), WithoutMetrics(),
+		WithAttributes(attribute.String("shared", "yes")),
+		WithAttributes(attribute.String("shared.1", "yes")),
+		WithAttributes(attribute.String("shared.2", "yes")),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			ctx, session := p.Before(context.TODO(), strconv.Itoa(i), db)
+			defer session.Close()
+			p.After(ctx, db.DriverName(), fmt.Sprintf("table_%d", i), -1, session, nil,
+				WithAttributes(attribute.Int("call", i)))
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, 3, len(p.attrs))
+
+	spans := sr.Ended()
+	require.Equal(t, calls, len(spans))
+
+	for _, s := range spans {
+		var calls []int64
+		for _, kv := range s.Attributes() {
+			if kv.Key == "call" {
+				calls = append(calls, kv.Value.AsInt64())
+			}
+		}
+		require.Equal(t, []int64{mustAtoi(t, s.Name())}, calls)
+
+		m := attrMap(s.Attributes())
+
+		table, ok := m[semconv.DBSQLTableKey]
+		require.True(t, ok)
+		require.Equal(t, "table_"+s.Name(), table.AsString())
+
+		shared, ok := m["shared"]
+		require.True(t, ok)
+		require.Equal(t, "yes", shared.AsString())
+	}
+}
+
+func mustAtoi(t *testing.T, s string) int64 {
+	i, err := strconv.ParseInt(s, 10, 64)
+	require.NoError(t, err)
+	return i
+}
+
+func TestOperationMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(tracetest.NewSpanRecorder()))
+
+	db, err := xorm.NewEngine(xormCore.SQLITE, "file:metrics?mode=memory&cache=shared")
+	require.NoError(t, err)
+
+	p := newPlugin(WithTracerProvider(provider), WithMeterProvider(meterProvider))
+
+	ctx, session := p.Before(context.TODO(), RawAsSpanName, db)
+	_, err = session.Exec("CREATE TABLE metrics_foo (id int)")
+	p.After(ctx, db.DriverName(), "metrics_foo", -1, session, err)
+	require.NoError(t, err)
+
+	ctx, session = p.Before(context.TODO(), QueryAsSpanName, db)
+	_, err = session.Query("SELECT foo_bar FROM metrics_foo")
+	p.After(ctx, db.DriverName(), "metrics_foo", -1, session, err)
+	require.Error(t, err)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.TODO(), &rm))
+	require.Equal(t, 1, len(rm.ScopeMetrics))
+	require.Equal(t, "github.com/dapings/opentelemetry-xorm/metrics", rm.ScopeMetrics[0].Scope.Name)
+
+	got := make(map[string]metricdata.Metrics)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		got[m.Name] = m
+	}
+
+	duration, ok := got["db.client.operation.duration"].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Equal(t, 2, len(duration.DataPoints))
+
+	count, ok := got["db.client.operations"].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	require.Equal(t, 2, len(count.DataPoints))
+
+	for _, dp := range count.DataPoints {
+		require.Equal(t, int64(1), dp.Value)
+
+		sys, ok := dp.Attributes.Value(semconv.DBSystemKey)
+		require.True(t, ok)
+		require.Equal(t, xormCore.SQLITE, sys.AsString())
+
+		table, ok := dp.Attributes.Value(semconv.DBSQLTableKey)
+		require.True(t, ok)
+		require.Equal(t, "metrics_foo", table.AsString())
+
+		operation, ok := dp.Attributes.Value(semconv.DBOperationKey)
+		require.True(t, ok)
+
+		_, hasErr := dp.Attributes.Value(errorType)
+		require.Equal(t, operation.AsString() == "select", hasErr)
+	}
+}
+
+func TestSemConvSpanName(t *testing.T) {
+	tests := []struct {
+		dbName, operation, table string
+		want                     string
+	}{
+		{dbName: "shop", operation: "select", table: "orders", want: "SELECT shop.orders"},
+		{dbName: "shop", operation: "select", want: "SELECT shop"},
+		{operation: "insert", table: "orders", want: "INSERT orders"},
+		{operation: "begin", want: "BEGIN"},
+		{dbName: "shop", want: "shop"},
+		{want: ""},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.want, SemConvSpanName(test.dbName)(test.operation, test.table, ""))
+	}
+}
+
+func TestSlowQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		opts      []Option
+		slow      bool
+		statement string
+	}{
+		{name: "slow", threshold: time.Nanosecond, slow: true, statement: "SELECT 42"},
+		{name: "slow without variables", threshold: time.Nanosecond, opts: []Option{WithoutQueryVariables()}, slow: true, statement: "SELECT ?"},
+		{name: "fast", threshold: time.Hour, opts: []Option{WithoutQueryVariables()}, slow: false, statement: "SELECT ?"},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+			reader := sdkmetric.NewManualReader()
+			meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+			db, err := xorm.NewEngine(xormCore.SQLITE, "file::memory:?cache=shared")
+			require.NoError(t, err)
+
+			p := newPlugin(append([]Option{WithTracerProvider(provider), WithMeterProvider(meterProvider),
+				WithSlowQueryThreshold(test.threshold)}, test.opts...)...)
+
+			ctx, session := p.Before(context.TODO(), RawAsSpanName, db)
+			_, err = session.Query("SELECT ?", 42)
+			p.After(ctx, db.DriverName(), "", -1, session, err)
+			require.NoError(t, err)
+
+			spans := sr.Ended()
+			require.Equal(t, 1, len(spans))
+
+			m := attrMap(spans[0].Attributes())
+
+			stmt, ok := m[semconv.DBStatementKey]
+			require.True(t, ok)
+			require.Equal(t, test.statement, stmt.AsString())
+
+			slow, ok := m[dbSlowQuery]
+			require.Equal(t, test.slow, ok)
+
+			var rm metricdata.ResourceMetrics
+			require.NoError(t, reader.Collect(context.TODO(), &rm))
+			var slowCount int64
+			for _, m := range rm.ScopeMetrics[0].Metrics {
+				if m.Name == "db.client.operations.slow" {
+					for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+						slowCount += dp.Value
+					}
+				}
+			}
+
+			if !test.slow {
+				require.Empty(t, spans[0].Events())
+				require.Equal(t, int64(0), slowCount)
+				return
+			}
+
+			require.True(t, slow.AsBool())
+			require.Equal(t, int64(1), slowCount)
+			require.Equal(t, 1, len(spans[0].Events()))
+
+			event := spans[0].Events()[0]
+			require.Equal(t, slowQueryEventName, event.Name)
+
+			m = attrMap(event.Attributes)
+
+			stmt, ok = m[semconv.DBStatementKey]
+			require.True(t, ok)
+			require.Equal(t, test.statement, stmt.AsString())
+
+			_, ok = m[dbQueryDuration]
+			require.True(t, ok)
+		})
+	}
+}
+
+func TestWaitTime(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	name, err := RegisterDriver(registerSQLite(), WithoutMetrics())
+	require.NoError(t, err)
+
+	db, err := xorm.NewEngine(name, "file:wait?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer db.Close()
+
+	p := New(db, WithTracerProvider(provider), WithMeterProvider(meterProvider))
+	defer p.Close()
+	db.SetMaxOpenConns(1)
+
+	// the holding session takes the only connection, the waiting one waits for it meanwhile.
+	hctx, holding := p.Before(context.TODO(), "holding", db)
+	require.NoError(t, holding.Begin())
+
+	wctx, waiting := p.Before(context.TODO(), "waiting", db)
+	done := make(chan error)
+	go func() {
+		_, err := waiting.Exec("SELECT 42")
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, holding.Commit())
+	require.NoError(t, <-done)
+
+	p.After(wctx, db.DriverName(), "", -1, waiting, nil)
+	p.After(hctx, db.DriverName(), "", -1, holding, nil)
+
+	waits := make(map[string]float64)
+	for _, s := range sr.Ended() {
+		if s.Name() == "holding" || s.Name() == "waiting" {
+			wait, ok := attrMap(s.Attributes())[dbConnWaitTime]
+			require.True(t, ok)
+			waits[s.Name()] = wait.AsFloat64()
+		}
+	}
+	require.Equal(t, 2, len(waits))
+	require.Less(t, waits["holding"], 0.01)
+	require.GreaterOrEqual(t, waits["waiting"], 0.02)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.TODO(), &rm))
+
+	var count uint64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "db.client.connections.wait_time" {
+				for _, dp := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+					count += dp.Count
+				}
+			}
+		}
+	}
+	require.Equal(t, uint64(2), count)
+}
+
+func TestConnectionTimeouts(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	name, err := RegisterDriver(registerSQLite(), WithoutMetrics())
+	require.NoError(t, err)
+
+	db, err := xorm.NewEngine(name, "file:timeouts?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer db.Close()
+
+	p := New(db, WithMeterProvider(meterProvider))
+	defer p.Close()
+	db.SetMaxOpenConns(1)
+
+	conn, err := db.DB().Conn(context.TODO())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	ctx, session := p.Before(ctx, RawAsSpanName, db)
+	_, err = session.Exec("SELECT 42")
+	p.After(ctx, db.DriverName(), "", -1, session, err)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.TODO(), &rm))
+
+	var timeouts int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "db.client.connections.timeouts" {
+				for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+					timeouts += dp.Value
+				}
+			}
+		}
+	}
+	require.Equal(t, int64(1), timeouts)
+}
+
+func TestWaitTimeUnknown(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	db, err := xorm.NewEngine(xormCore.SQLITE, "file:nowait?mode=memory&cache=shared")
+	require.NoError(t, err)
+	defer db.Close()
+
+	p := New(db, WithTracerProvider(provider), WithoutMetrics())
+
+	ctx, session := p.Before(context.TODO(), RawAsSpanName, db)
+	_, err = session.Exec("SELECT 42")
+	p.After(ctx, db.DriverName(), "", -1, session, err)
+	require.NoError(t, err)
+
+	spans := sr.Ended()
+	require.Equal(t, 1, len(spans))
+	_, ok := attrMap(spans[0].Attributes())[dbConnWaitTime]
+	require.False(t, ok) // the connections are not traced
+}
+
+func TestWaitTimeConnect(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	base := fmt.Sprintf("fake-%d", testDrivers.Add(1))
+	var args []driver.Value
+	sql.Register(base, fakeDriver{stmt: fakeStmt{inputs: 0, args: &args}, connect: 20 * time.Millisecond})
+	xormCore.RegisterDriver(base, xormCore.QueryDriver(xormCore.SQLITE))
+
+	name, err := RegisterDriver(base, WithoutMetrics())
+	require.NoError(t, err)
+
+	db, err := xorm.NewEngine(name, "file:connect")
+	require.NoError(t, err)
+	defer db.Close()
+
+	p := New(db, WithTracerProvider(provider), WithoutMetrics())
+
+	// the session waits for the pool to open its first connection.
+	ctx, session := p.Before(context.TODO(), RawAsSpanName, db)
+	_, err = session.Exec("SELECT 42")
+	p.After(ctx, db.DriverName(), "", -1, session, err)
+	require.NoError(t, err)
+
+	spans := sr.Ended()
+	require.Equal(t, 1, len(spans))
+	wait, ok := attrMap(spans[0].Attributes())[dbConnWaitTime]
+	require.True(t, ok)
+	require.GreaterOrEqual(t, wait.AsFloat64(), 0.02)
+}
+
+func TestExplain(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	db, err := xorm.NewEngine(xormCore.SQLITE, "file:explain?mode=memory&cache=shared")
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE explain_user (id INTEGER PRIMARY KEY, name TEXT)")
+	require.NoError(t, err)
+
+	p := newPlugin(WithTracerProvider(provider), WithoutMetrics())
+	explain := WithExplain(ExplainConfig{Threshold: time.Nanosecond, Interval: time.Hour})
+
+	for _, query := range []string{
+		"INSERT INTO explain_user (name) VALUES (?)",
+		"SELECT * FROM explain_user WHERE name = ?",
+		"SELECT * FROM explain_user WHERE name = ?", // rate-limited
+	} {
+		ctx, session := p.Before(context.TODO(), RawAsSpanName, db)
+		_, err = session.Exec(query, "foo")
+		p.After(ctx, db.DriverName(), "explain_user", -1, session, err, explain)
+		require.NoError(t, err)
+	}
+	p.explains.Wait()
+
+	spans := sr.Ended()
+	require.Equal(t, 4, len(spans))
+
+	var explained []sdktrace.ReadOnlySpan
+	for _, s := range spans {
+		if s.Name() == ExplainAsSpanName {
+			explained = append(explained, s)
+		}
+		if s.Name() == RawAsSpanName {
+			require.Empty(t, s.Events())
+		}
+	}
+	require.Equal(t, 1, len(explained))
+	require.Equal(t, spans[1].SpanContext().SpanID(), explained[0].Parent().SpanID())
+	require.Equal(t, 1, len(explained[0].Events()))
+
+	event := explained[0].Events()[0]
+	require.Equal(t, queryPlanEventName, event.Name)
+
+	m := attrMap(event.Attributes)
+
+	stmt, ok := m[semconv.DBStatementKey]
+	require.True(t, ok)
+	require.Equal(t, "EXPLAIN QUERY PLAN SELECT * FROM explain_user WHERE name = ?", stmt.AsString())
+
+	plan, ok := m[dbQueryPlan]
+	require.True(t, ok)
+	require.Contains(t, plan.AsString(), "SCAN")
+}
+
+func TestExplainRefused(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "without query variables", opts: []Option{WithoutQueryVariables()}},
+		{name: "sanitizer", opts: []Option{WithStatementSanitizer(logger.ObfuscateSQL)}},
+		{name: "redaction policy", opts: []Option{WithRedactionPolicy(&logger.RedactionPolicy{Positions: []int{0}})}},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+			db, err := xorm.NewEngine(xormCore.SQLITE, "file::memory:?cache=shared")
+			require.NoError(t, err)
+
+			p := newPlugin(append([]Option{WithTracerProvider(provider), WithoutMetrics(),
+				WithExplain(ExplainConfig{Threshold: time.Nanosecond})}, test.opts...)...)
+
+			ctx, session := p.Before(context.TODO(), RawAsSpanName, db)
+			_, err = session.Query("SELECT ?", "secret")
+			p.After(ctx, db.DriverName(), "", -1, session, err)
+			require.NoError(t, err)
+			p.explains.Wait()
+
+			spans := sr.Ended()
+			require.Equal(t, 1, len(spans))
+			require.Equal(t, RawAsSpanName, spans[0].Name())
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	var engines []*xorm.Engine
+	for _, dsn := range []string{"file:close?mode=memory&cache=shared", "file:close?mode=memory&cache=shared"} {
+		db, err := xorm.NewEngine(xormCore.SQLITE, dsn)
+		require.NoError(t, err)
+		engines = append(engines, db)
+	}
+
+	p := newPlugin(WithMeterProvider(meterProvider), WithDBName("close"))
+	for _, db := range engines {
+		p.register(db)
+		p.register(db) // registering twice must not report the pool twice
+	}
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.TODO(), &rm))
+
+	names := make(map[string]bool)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "db.client.connections.max" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				name, ok := dp.Attributes.Value("pool.name")
+				require.True(t, ok)
+				names[name.AsString()] = true
+			}
+		}
+	}
+	require.Equal(t, map[string]bool{"sqlite3/close": true, "sqlite3/close#2": true}, names)
+
+	require.NoError(t, p.Close())
+
+	rm = metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.TODO(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			require.NotEqual(t, "db.client.connections.max", m.Name)
+		}
+	}
+}
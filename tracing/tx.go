@@ -0,0 +1,185 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-xorm/xorm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TxAsSpanName = "xorm:tx" // a transaction, from Begin to Commit or Rollback
+
+	TxOutcomeCommit   = "commit"
+	TxOutcomeRollback = "rollback"
+)
+
+var (
+	dbTxOutcome    = attribute.Key("db.transaction.outcome")
+	dbTxStatements = attribute.Key("db.transaction.statements")
+	dbTxDuration   = attribute.Key("db.transaction.duration")
+)
+
+// Tx is a traced transaction, its span is the parent of the statement spans.
+// The statements run through Do, or Before/After, so that each one gets a child span and is counted,
+// the transaction session is not exposed otherwise.
+type Tx struct {
+	session *xorm.Session
+
+	p          *Plugin
+	ctx        context.Context
+	span       trace.Span
+	driverName string
+	start      time.Time
+	statements int64
+	err        error // the first statement error, which causes the rollback
+	ended      bool
+}
+
+// BeginTx begins a traced transaction on the engine with the default Plugin.
+func BeginTx(ctx context.Context, engine *xorm.Engine) (*Tx, error) {
+	return defaultXORMPlugin.BeginTx(ctx, engine)
+}
+
+// BeginTx begins a traced transaction on the engine.
+func (p *Plugin) BeginTx(ctx context.Context, engine *xorm.Engine) (*Tx, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(p.attrs)+8)
+	if peer, ok := p.peers.Load(engine.DB().DB); ok {
+		attrs = append(attrs, peer.([]attribute.KeyValue)...)
+	}
+	attrs = append(attrs, p.attrs...)
+	if sys := dbSystem(engine.DriverName()); sys.Valid() {
+		attrs = append(attrs, sys)
+	}
+
+	start := time.Now()
+	ctx, span := p.tracer.Start(withTransaction(ctx), TxAsSpanName, trace.WithSpanKind(trace.SpanKindClient), trace.WithTimestamp(start),
+		trace.WithAttributes(attrs...))
+
+	session := engine.NewSession().Context(ctx)
+	if err := session.Begin(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		session.Close()
+		return nil, err
+	}
+
+	return &Tx{
+		session:    session,
+		p:          p,
+		ctx:        ctx,
+		span:       span,
+		driverName: engine.DriverName(),
+		start:      start,
+	}, nil
+}
+
+type transactionKey struct{}
+
+// withTransaction marks the statements of a Tx, which hold its connection.
+func withTransaction(ctx context.Context) context.Context {
+	return context.WithValue(ctx, transactionKey{}, true)
+}
+
+func inTransaction(ctx context.Context) bool {
+	in, _ := ctx.Value(transactionKey{}).(bool)
+	return in
+}
+
+// TxContext returns the context carrying the transaction span.
+func (tx *Tx) TxContext() context.Context {
+	return tx.ctx
+}
+
+// Before starts a statement span as a child of the transaction span, the returned session is the transaction one.
+func (tx *Tx) Before(spanName string) (context.Context, *xorm.Session) {
+	tx.statements++
+
+	ctx, _ := tx.p.tracer.Start(withStartTime(tx.ctx), spanName, trace.WithSpanKind(trace.SpanKindClient),
+		tx.p.startAttributes(tx.driverName, nil))
+
+	return ctx, tx.session.Context(ctx)
+}
+
+// Do runs fn on the transaction session as a statement, in a child span of the transaction span
+// named after the executed SQL, e.g. QueryAsSpanName. fn returns the rows affected, -1 when unknown.
+func (tx *Tx) Do(fn func(session *xorm.Session) (rowsAffected int64, err error)) error {
+	ctx, session := tx.Before(RawAsSpanName)
+
+	rowsAffected, err := fn(session)
+
+	query, _ := session.LastSQL()
+	trace.SpanFromContext(ctx).SetName(operationSpanName(dbOperation(query)))
+	tx.After(ctx, dbTable(query), rowsAffected, err)
+
+	return err
+}
+
+// After collects the trace data of the statement started by Before.
+func (tx *Tx) After(ctx context.Context, tableName string, rowsAffected int64, txErr error, opts ...Option) {
+	tx.p.after(ctx, tx.driverName, tableName, rowsAffected, tx.session, txErr, opts...)
+	tx.session.Context(tx.ctx)
+
+	if tx.err == nil && tx.p.isError(ctx, txErr) {
+		tx.err = txErr
+	}
+}
+
+// Commit commits the transaction and ends its span.
+func (tx *Tx) Commit() error {
+	err := tx.session.Commit()
+	tx.end(TxOutcomeCommit, err)
+	return err
+}
+
+// Rollback rolls back the transaction and ends its span,
+// the span is an error one when the rollback fails or a statement has failed.
+func (tx *Tx) Rollback() error {
+	err := tx.session.Rollback()
+
+	cause := err
+	if cause == nil {
+		cause = tx.err
+	}
+	tx.end(TxOutcomeRollback, cause)
+
+	return err
+}
+
+// Close rolls back the transaction if it is neither committed nor rolled back, then closes the session.
+func (tx *Tx) Close() {
+	if !tx.ended {
+		_ = tx.Rollback()
+	}
+	tx.session.Close()
+}
+
+func (tx *Tx) end(outcome string, err error) {
+	if tx.ended {
+		return
+	}
+	tx.ended = true
+
+	tx.span.SetAttributes(
+		dbTxOutcome.String(outcome),
+		dbTxStatements.Int64(tx.statements),
+		dbTxDuration.Float64(time.Since(tx.start).Seconds()),
+	)
+
+	if tx.p.isError(tx.ctx, err) {
+		tx.span.RecordError(err)
+		tx.span.SetStatus(codes.Error, err.Error())
+	} else {
+		tx.span.SetStatus(codes.Ok, "")
+	}
+
+	tx.span.End()
+}
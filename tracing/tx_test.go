@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-xorm/xorm"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	xormCore "xorm.io/core"
+)
+
+func TestTx(t *testing.T) {
+	tests := []struct {
+		name     string
+		do       func(t *testing.T, tx *Tx)
+		outcome  string
+		stmts    int64
+		spanCode codes.Code
+	}{
+		{
+			name: "commit",
+			do: func(t *testing.T, tx *Tx) {
+				ctx, session := tx.Before(CreatAsSpanName)
+				_, err := session.Exec("INSERT INTO tx_foo (id) VALUES (?)", 1)
+				tx.After(ctx, "tx_foo", 1, err)
+				require.NoError(t, err)
+
+				require.NoError(t, tx.Do(func(session *xorm.Session) (int64, error) {
+					return session.Table("tx_foo").Where("id = ?", 1).Update(map[string]any{"id": 2})
+				}))
+
+				require.NoError(t, tx.Commit())
+			},
+			outcome:  TxOutcomeCommit,
+			stmts:    2,
+			spanCode: codes.Ok,
+		},
+		{
+			name: "rollback caused by an error",
+			do: func(t *testing.T, tx *Tx) {
+				ctx, session := tx.Before(RawAsSpanName)
+				_, err := session.Exec("INSERT INTO tx_bar (id) VALUES (?)", 1)
+				tx.After(ctx, "tx_bar", -1, err)
+				require.Error(t, err)
+
+				require.NoError(t, tx.Rollback())
+			},
+			outcome:  TxOutcomeRollback,
+			stmts:    1,
+			spanCode: codes.Error,
+		},
+		{
+			name: "rollback on close",
+			do: func(t *testing.T, tx *Tx) {
+				tx.Close()
+			},
+			outcome:  TxOutcomeRollback,
+			stmts:    0,
+			spanCode: codes.Ok,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+			db, err := xorm.NewEngine(xormCore.SQLITE, "file:tx?mode=memory&cache=shared")
+			require.NoError(t, err)
+			defer db.Close()
+
+			_, err = db.Exec("CREATE TABLE IF NOT EXISTS tx_foo (id int)")
+			require.NoError(t, err)
+
+			p := newPlugin(WithTracerProvider(provider), WithoutMetrics())
+
+			tx, err := p.BeginTx(context.TODO(), db)
+			require.NoError(t, err)
+			test.do(t, tx)
+			tx.Close()
+
+			spans := sr.Ended()
+			require.Equal(t, int(test.stmts)+1, len(spans))
+
+			txSpan := spans[len(spans)-1]
+			require.Equal(t, TxAsSpanName, txSpan.Name())
+			require.Equal(t, test.spanCode, txSpan.Status().Code)
+
+			for _, s := range spans[:len(spans)-1] {
+				require.Equal(t, txSpan.SpanContext().SpanID(), s.Parent().SpanID())
+			}
+			if test.outcome == TxOutcomeCommit {
+				update := attrMap(spans[1].Attributes())
+				require.Equal(t, UpdateAsSpanName, spans[1].Name())
+				require.Equal(t, "tx_foo", update[semconv.DBSQLTableKey].AsString())
+				require.Equal(t, int64(1), update[dbRowsAffected].AsInt64())
+			}
+
+			m := attrMap(txSpan.Attributes())
+			require.Equal(t, xormCore.SQLITE, m[semconv.DBSystemKey].AsString())
+
+			outcome, ok := m[dbTxOutcome]
+			require.True(t, ok)
+			require.Equal(t, test.outcome, outcome.AsString())
+
+			stmts, ok := m[dbTxStatements]
+			require.True(t, ok)
+			require.Equal(t, test.stmts, stmts.AsInt64())
+
+			_, ok = m[dbTxDuration]
+			require.True(t, ok)
+		})
+	}
+}